@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/everfir/metrics-go/structs/metric_info"
+)
+
+// generator 为一组指标生成带类型的上报函数
+type generator struct {
+	pkg      string
+	source   string
+	metrics  []metric_info.MetricInfo
+	register bool // 是否生成 Register，仅在定义来自目录文件时需要
+}
+
+type metricData struct {
+	Info     metric_info.MetricInfo
+	Func     string      // 上报函数名
+	Const    string      // 指标名常量
+	Params   []labelData // 标签参数，按照 Labels 的声明顺序
+	Literal  string      // MetricInfo 字面量
+	Observed string      // 上报动作的描述
+	Help     string      // 用于注释的 Help，换行已合并为空格
+}
+
+type labelData struct {
+	Name  string
+	Param string
+}
+
+var tmpl = template.Must(template.New("metrics").Parse(`// Code generated by metricsgen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+{{- if .NeedTime}}
+	"time"
+{{- end}}
+
+	"github.com/everfir/metrics-go"
+	"github.com/everfir/metrics-go/structs/metric_info"
+)
+
+const (
+{{- range .Metrics}}
+	{{.Const}} metric_info.MetricName = {{printf "%q" .Info.Name}}
+{{- end}}
+)
+{{if .Register}}
+// Definitions 返回所有指标的定义
+func Definitions() []metric_info.MetricInfo {
+	return []metric_info.MetricInfo{
+{{- range .Metrics}}
+		{{.Literal}},
+{{- end}}
+	}
+}
+
+// Register 注册所有指标
+func Register(ctx context.Context) error {
+	for _, info := range Definitions() {
+		if err := metrics.Register(ctx, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+{{end}}
+{{- range .Metrics}}
+{{if eq .Info.Type.String "counter"}}
+// {{.Func}} 将 {{.Info.Name}} 加 1{{if .Help}}：{{.Help}}{{end}}
+func {{.Func}}(ctx context.Context{{range .Params}}, {{.Param}} string{{end}}) error {
+	return {{.Func}}Add(ctx, 1{{range .Params}}, {{.Param}}{{end}})
+}
+
+// {{.Func}}Add 将 {{.Info.Name}} 加上 value
+func {{.Func}}Add(ctx context.Context, value float64{{range .Params}}, {{.Param}} string{{end}}) error {
+{{- else}}
+// {{.Func}} {{.Observed}} {{.Info.Name}}{{if .Help}}：{{.Help}}{{end}}
+func {{.Func}}(ctx context.Context, value float64{{range .Params}}, {{.Param}} string{{end}}) error {
+{{- end}}
+	return metrics.Report(ctx, {{.Const}}, {{if .Params}}map[string]string{
+{{- range .Params}}
+		{{printf "%q" .Name}}: {{.Param}},
+{{- end}}
+	}{{else}}nil{{end}}, value)
+}
+{{end}}`))
+
+func (g *generator) generate() ([]byte, error) {
+	data := struct {
+		Source   string
+		Package  string
+		Register bool
+		NeedTime bool
+		Metrics  []metricData
+	}{Source: g.source, Package: g.pkg, Register: g.register}
+
+	// 生成的包级标识符 -> 来源，来源为空表示 Definitions、Register 等固定的函数
+	idents := make(map[string]metric_info.MetricName)
+	declare := func(ident string, name metric_info.MetricName) error {
+		if prev, exists := idents[ident]; exists {
+			if prev == "" {
+				return fmt.Errorf("metric %q maps to %s, which is reserved by the generated code", name, ident)
+			}
+			return fmt.Errorf("metrics %q and %q both map to identifier %s", prev, name, ident)
+		}
+		idents[ident] = name
+		return nil
+	}
+	if g.register {
+		idents["Definitions"] = ""
+		idents["Register"] = ""
+	}
+	for _, info := range g.metrics {
+		m := metricData{
+			Info:    info,
+			Func:    funcName(info),
+			Literal: literal(info),
+			Help:    strings.Join(strings.Fields(info.Help), " "),
+		}
+		m.Const = m.Func + "Name"
+		declared := []string{m.Func, m.Const}
+		if info.Type == metric_info.Counter {
+			declared = append(declared, m.Func+"Add")
+		}
+		for _, ident := range declared {
+			if err := declare(ident, info.Name); err != nil {
+				return nil, err
+			}
+		}
+
+		switch info.Type {
+		case metric_info.Gauge:
+			m.Observed = "设置"
+		case metric_info.Histogram, metric_info.Summary:
+			m.Observed = "观察"
+		}
+		// 参数不能与函数体中用到的标识符（包括导入的包名）同名
+		params := map[string]bool{"ctx": true, "value": true, "metrics": true, "metric_info": true}
+		for _, label := range info.Labels {
+			param := paramName(label)
+			for params[param] {
+				param += "_"
+			}
+			params[param] = true
+			m.Params = append(m.Params, labelData{Name: label, Param: param})
+		}
+		if g.register && (info.TTL > 0 || info.MaxAge > 0) {
+			data.NeedTime = true
+		}
+		data.Metrics = append(data.Metrics, m)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return code, nil
+}
+
+// funcName 将 orders_created_total 转换为 OrdersCreated
+func funcName(info metric_info.MetricInfo) string {
+	name := info.Name.String()
+	if info.Type == metric_info.Counter {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == ':' || r == '-' || r == '.' }) {
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	ret := b.String()
+	if ret == "" || !unicode.IsLetter([]rune(ret)[0]) {
+		ret = "Metric" + ret
+	}
+	return ret
+}
+
+// paramName 将 user_agent 转换为 userAgent，并避开 Go 关键字
+func paramName(label string) string {
+	parts := strings.FieldsFunc(label, func(r rune) bool { return r == '_' })
+	if len(parts) == 0 {
+		return "label"
+	}
+	var b strings.Builder
+	for i, part := range parts {
+		runes := []rune(part)
+		if i == 0 {
+			runes[0] = unicode.ToLower(runes[0])
+		} else {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+		b.WriteString(string(runes))
+	}
+	ret := b.String()
+	if token.IsKeyword(ret) || !unicode.IsLetter([]rune(ret)[0]) {
+		ret = "l" + strings.ToUpper(ret[:1]) + ret[1:]
+	}
+	return ret
+}
+
+// literal 生成 MetricInfo 的 Go 字面量
+func literal(info metric_info.MetricInfo) string {
+	var b strings.Builder
+	b.WriteString("{\n")
+	field := func(name, value string) {
+		fmt.Fprintf(&b, "%s: %s,\n", name, value)
+	}
+	field("Type", "metric_info."+typeConst(info.Type))
+	field("Name", fmt.Sprintf("%q", info.Name))
+	field("Help", fmt.Sprintf("%q", info.Help))
+	if info.Unit != "" {
+		field("Unit", fmt.Sprintf("%q", info.Unit))
+	}
+	if len(info.Labels) > 0 {
+		field("Labels", fmt.Sprintf("%#v", info.Labels))
+	}
+	if len(info.LabelDefaults) > 0 {
+		field("LabelDefaults", fmt.Sprintf("%#v", info.LabelDefaults))
+	}
+	if len(info.LabelAllowlist) > 0 {
+		field("LabelAllowlist", fmt.Sprintf("%#v", info.LabelAllowlist))
+	}
+	if len(info.ConstLabels) > 0 {
+		field("ConstLabels", fmt.Sprintf("%#v", info.ConstLabels))
+	}
+	if len(info.Buckets) > 0 {
+		field("Buckets", fmt.Sprintf("%#v", info.Buckets))
+	}
+	if len(info.Objectives) > 0 {
+		field("Objectives", fmt.Sprintf("%#v", info.Objectives))
+	}
+	if info.MaxAge > 0 {
+		field("MaxAge", durationLiteral(info.MaxAge))
+	}
+	if info.AgeBuckets > 0 {
+		field("AgeBuckets", fmt.Sprintf("%d", info.AgeBuckets))
+	}
+	if info.BufCap > 0 {
+		field("BufCap", fmt.Sprintf("%d", info.BufCap))
+	}
+	if info.MaxSeries > 0 {
+		field("MaxSeries", fmt.Sprintf("%d", info.MaxSeries))
+	}
+	if info.TTL > 0 {
+		field("TTL", durationLiteral(info.TTL))
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// durationLiteral 生成 time.Duration 的 Go 表达式，如 90 * time.Second
+func durationLiteral(d time.Duration) string {
+	for _, unit := range []struct {
+		d    time.Duration
+		name string
+	}{{time.Hour, "time.Hour"}, {time.Minute, "time.Minute"}, {time.Second, "time.Second"}, {time.Millisecond, "time.Millisecond"}} {
+		if d%unit.d == 0 {
+			return fmt.Sprintf("%d * %s", d/unit.d, unit.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", int64(d))
+}
+
+func typeConst(t metric_info.MetricType) string {
+	switch t {
+	case metric_info.Gauge:
+		return "Gauge"
+	case metric_info.Histogram:
+		return "Histogram"
+	case metric_info.Summary:
+		return "Summary"
+	default:
+		return "Counter"
+	}
+}
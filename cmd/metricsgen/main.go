@@ -0,0 +1,81 @@
+// metricsgen 根据指标目录（YAML）或带注解的 Go MetricInfo 声明生成带类型的上报函数，
+// 使标签名称与顺序在编译期得到检查，而不是自由拼写 map[string]string。
+//
+// 用法：
+//
+//	metricsgen -catalog metrics.yaml -pkg ordermetrics -out ordermetrics/metrics_gen.go
+//	metricsgen -src ./internal/order -pkg ordermetrics -out ordermetrics/metrics_gen.go
+//
+// 在 Go 源码中，紧邻 MetricInfo 字面量上一行的 "//metricsgen:metric" 注释表示需要为其生成代码，
+// 字面量中的 Type、Name、Help、Labels 必须是常量表达式。
+//
+// 也可以配合 go:generate 使用：
+//
+//	//go:generate go run github.com/everfir/metrics-go/cmd/metricsgen -catalog metrics.yaml -pkg ordermetrics -out metrics_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/everfir/metrics-go/structs/catalog"
+)
+
+func main() {
+	catalogFile := flag.String("catalog", "", "YAML metric catalog to read")
+	srcDir := flag.String("src", "", "Go package directory with //metricsgen:metric annotated MetricInfo declarations")
+	pkg := flag.String("pkg", "", "package name of the generated file (default: directory name of -out)")
+	out := flag.String("out", "", "output file (default: stdout)")
+	flag.Parse()
+
+	if err := run(*catalogFile, *srcDir, *pkg, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "metricsgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(catalogFile, srcDir, pkg, out string) error {
+	if (catalogFile == "") == (srcDir == "") {
+		return fmt.Errorf("exactly one of -catalog and -src is required")
+	}
+	if pkg == "" {
+		if out == "" {
+			return fmt.Errorf("-pkg is required when writing to stdout")
+		}
+		abs, err := filepath.Abs(out)
+		if err != nil {
+			return err
+		}
+		pkg = filepath.Base(filepath.Dir(abs))
+	}
+
+	g := generator{pkg: pkg}
+	if catalogFile != "" {
+		c, err := catalog.Load(catalogFile)
+		if err != nil {
+			return err
+		}
+		g.source = catalogFile
+		g.metrics = c.Metrics()
+		g.register = true
+	} else {
+		metrics, err := parseSource(srcDir)
+		if err != nil {
+			return err
+		}
+		g.source = srcDir
+		g.metrics = metrics
+	}
+
+	code, err := g.generate()
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(out, code, 0o644)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/everfir/metrics-go/structs/metric_info"
+)
+
+// annotation 标记需要生成代码的 MetricInfo 字面量，必须紧邻字面量所在行的上一行
+const annotation = "//metricsgen:metric"
+
+// parseSource 解析目录下（不含测试文件）带注解的 MetricInfo 字面量
+func parseSource(dir string) ([]metric_info.MetricInfo, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*ast.File
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return fset.Position(files[i].Pos()).Filename < fset.Position(files[j].Pos()).Filename
+	})
+
+	consts := collectStringConsts(files)
+
+	var (
+		ret  []metric_info.MetricInfo
+		errs []string
+	)
+	for _, file := range files {
+		annotated := make(map[int]bool)
+		for _, group := range file.Comments {
+			for _, c := range group.List {
+				if strings.HasPrefix(c.Text, annotation) {
+					annotated[fset.Position(c.End()).Line+1] = true
+				}
+			}
+		}
+		if len(annotated) == 0 {
+			continue
+		}
+
+		// []metric_info.MetricInfo{{...}} 中的元素省略了类型
+		elided := make(map[*ast.CompositeLit]bool)
+		ast.Inspect(file, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			if arr, ok := lit.Type.(*ast.ArrayType); ok && isMetricInfo(arr.Elt) {
+				for _, elt := range lit.Elts {
+					if child, ok := elt.(*ast.CompositeLit); ok && child.Type == nil {
+						elided[child] = true
+					}
+				}
+				return true
+			}
+			if !isMetricInfo(lit.Type) && !elided[lit] {
+				return true
+			}
+			pos := fset.Position(lit.Pos())
+			if !annotated[pos.Line] {
+				return true
+			}
+			info, err := metricInfoFromLiteral(lit, consts)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s:%d: %v", filepath.Base(pos.Filename), pos.Line, err))
+				return false
+			}
+			ret = append(ret, info)
+			return false
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no %s annotated MetricInfo found in %s", annotation, dir)
+	}
+	return ret, nil
+}
+
+// isMetricInfo 判断类型表达式是否为 metric_info.MetricInfo、MetricInfo 或其指针
+func isMetricInfo(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return isMetricInfo(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name == "MetricInfo"
+	case *ast.Ident:
+		return t.Name == "MetricInfo"
+	}
+	return false
+}
+
+// collectStringConsts 收集包级别的字符串常量，用于解析以常量表示的指标名
+func collectStringConsts(files []*ast.File) map[string]string {
+	consts := make(map[string]string)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if i >= len(vs.Values) {
+						continue
+					}
+					if value, err := stringValue(vs.Values[i], nil); err == nil {
+						consts[name.Name] = value
+					}
+				}
+			}
+		}
+	}
+	return consts
+}
+
+func metricInfoFromLiteral(lit *ast.CompositeLit, consts map[string]string) (info metric_info.MetricInfo, err error) {
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return info, fmt.Errorf("MetricInfo literal must use keyed fields")
+		}
+		key, _ := kv.Key.(*ast.Ident)
+		if key == nil {
+			continue
+		}
+		switch key.Name {
+		case "Type":
+			sel, ok := kv.Value.(*ast.SelectorExpr)
+			if !ok {
+				return info, fmt.Errorf("Type must be metric_info.Counter, Gauge, Histogram or Summary")
+			}
+			if info.Type, err = metric_info.ParseMetricType(sel.Sel.Name); err != nil {
+				return info, err
+			}
+		case "Name":
+			name, err := stringValue(kv.Value, consts)
+			if err != nil {
+				return info, fmt.Errorf("Name: %w", err)
+			}
+			info.Name = metric_info.NewMetricName(name)
+		case "Help":
+			if info.Help, err = stringValue(kv.Value, consts); err != nil {
+				return info, fmt.Errorf("Help: %w", err)
+			}
+		case "Labels":
+			labels, ok := kv.Value.(*ast.CompositeLit)
+			if !ok {
+				return info, fmt.Errorf("Labels must be a []string literal")
+			}
+			for _, l := range labels.Elts {
+				label, err := stringValue(l, consts)
+				if err != nil {
+					return info, fmt.Errorf("Labels: %w", err)
+				}
+				info.Labels = append(info.Labels, label)
+			}
+		}
+	}
+	if info.Name == "" {
+		return info, fmt.Errorf("MetricInfo literal has no Name")
+	}
+	return info, nil
+}
+
+// stringValue 求值字符串字面量、常量以及 MetricName("x")、NewMetricName("x") 形式的表达式
+func stringValue(expr ast.Expr, consts map[string]string) (string, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.STRING {
+			return strconv.Unquote(e.Value)
+		}
+	case *ast.Ident:
+		if value, ok := consts[e.Name]; ok {
+			return value, nil
+		}
+	case *ast.CallExpr:
+		if len(e.Args) == 1 {
+			return stringValue(e.Args[0], consts)
+		}
+	case *ast.ParenExpr:
+		return stringValue(e.X, consts)
+	}
+	return "", fmt.Errorf("expression is not a constant string")
+}
@@ -2,35 +2,84 @@ package metrics
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
 
 	"github.com/everfir/logger-go"
 	"github.com/everfir/logger-go/structs/field"
+	"github.com/everfir/metrics-go/structs/catalog"
 	"github.com/everfir/metrics-go/structs/config"
 	"github.com/everfir/metrics-go/structs/metric_info"
+	pm "github.com/everfir/metrics-go/structs/metrics"
 	"github.com/everfir/metrics-go/structs/reporter"
 )
 
 var (
-	r    reporter.MetricsReporter
-	once sync.Once
+	r        reporter.MetricsReporter
+	once     sync.Once
+	catalogs []*catalog.Catalog
+	debug    bool // 是否为每次上报输出 Debug 日志
+)
+
+// Version 与 Commit 可以在编译时通过 ldflags 注入，作为 build_info 指标的标签：
+//
+//	go build -ldflags "-X github.com/everfir/metrics-go.Version=v1.2.3 -X github.com/everfir/metrics-go.Commit=abc123"
+var (
+	Version string
+	Commit  string
 )
 
 const (
-	EnvNamespace = "Namespace"
-	EnvSystem    = "System"
+	EnvNamespace = config.EnvNamespace
+	EnvSystem    = config.EnvSystem
 )
 
-// Init 初始化 metrics 系统
+var (
+	// ErrMetricNotFound 表示上报的指标尚未注册
+	ErrMetricNotFound = pm.ErrMetricNotFound
+	// ErrInvalidLabels 表示上报的标签与指标声明的标签不一致
+	ErrInvalidLabels = pm.ErrInvalidLabels
+	// ErrAlreadyRegistered 表示同名指标已经以不同的定义注册
+	ErrAlreadyRegistered = pm.ErrAlreadyRegistered
+	// ErrInvalidName 表示指标名或标签名不符合 Prometheus 的命名规范
+	ErrInvalidName = pm.ErrInvalidName
+	// ErrQueueFull 表示异步模式下队列已满，本次上报被丢弃
+	ErrQueueFull = pm.ErrQueueFull
+	// ErrUnsupportedOperation 表示指标类型不支持该操作，如对直方图调用 Add
+	ErrUnsupportedOperation = pm.ErrUnsupportedOperation
+)
+
+// Init 初始化 metrics 系统，配置优先级：默认值 < 配置文件 < 环境变量 < opts
 func Init(opts ...Option) error {
 	cfg := &config.MetricsConfig{
-		ReportType: config.CollectorType, // 默认使用 Collector 模式
-		Port:       10083,                // 默认端口
+		ReportType:         config.CollectorType, // 默认使用 Collector 模式
+		Port:               10083,                // 默认端口
+		GoCollector:        true,
+		ProcessCollector:   true,
+		BuildInfoCollector: true,
+		Version:            Version,
+		Commit:             Commit,
+	}
+
+	// 配置文件路径本身也可以由 Option 或环境变量指定，需要先确定
+	probe := *cfg
+	for _, opt := range opts {
+		opt(&probe)
+	}
+	configFile := probe.ConfigFile
+	if configFile == "" {
+		configFile = os.Getenv(config.EnvConfigFile)
+	}
+	if configFile != "" {
+		if err := cfg.LoadFile(configFile); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+	if err := cfg.LoadEnv(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
 	}
-	cfg.Namespace = os.Getenv(EnvNamespace)
-	cfg.Subsystem = os.Getenv(EnvSystem)
 
 	for _, opt := range opts {
 		opt(cfg)
@@ -40,20 +89,74 @@ func Init(opts ...Option) error {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	var err error
+	loaded, err := loadCatalogs(cfg.CatalogFiles)
+	if err != nil {
+		return fmt.Errorf("invalid metric catalog: %w", err)
+	}
+
+	initialized := false
 	once.Do(func() {
+		initialized = true
+		debug = cfg.Debug
 		switch cfg.ReportType {
 		case config.CollectorType:
-			r = reporter.NewCollectorReporter(cfg.Namespace, cfg.Subsystem, cfg.Port)
+			r = reporter.NewCollectorReporterWithConfig(cfg)
 		case config.PushgatewayType:
-			r = reporter.NewPushgatewayReporter(cfg.Namespace, cfg.Subsystem, cfg.PushAddr, cfg.JobName, cfg.PushInterval)
+			r = reporter.NewPushgatewayReporterWithConfig(cfg)
+		case config.FileType:
+			r = reporter.NewFileReporterWithConfig(cfg)
 		default:
 			err = fmt.Errorf("invalid report type")
 			return
 		}
 	})
+	if err != nil || !initialized {
+		return err
+	}
 
-	return err
+	// 注册指标目录中的所有指标
+	catalogs = loaded
+	var errs []error
+	for _, c := range catalogs {
+		for _, info := range c.Metrics() {
+			errs = append(errs, Register(context.TODO(), info))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadCatalogs 加载所有指标目录文件，不同文件中不能出现同名指标
+func loadCatalogs(files []string) ([]*catalog.Catalog, error) {
+	var (
+		ret     []*catalog.Catalog
+		errs    []error
+		defined = make(map[metric_info.MetricName]string)
+	)
+	for _, file := range files {
+		c, err := catalog.Load(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, info := range c.Metrics() {
+			if prev, exists := defined[info.Name]; exists {
+				errs = append(errs, fmt.Errorf("%s: metric %q already defined in %s", file, info.Name, prev))
+			}
+			defined[info.Name] = file
+		}
+		ret = append(ret, c)
+	}
+	return ret, errors.Join(errs...)
+}
+
+// Lookup 在 Init 时加载的指标目录中查找指标定义
+func Lookup(name metric_info.MetricName) (metric_info.MetricInfo, bool) {
+	for _, c := range catalogs {
+		if info, ok := c.Lookup(name); ok {
+			return info, true
+		}
+	}
+	return metric_info.MetricInfo{}, false
 }
 
 // Close 优雅地关闭metrics系统
@@ -64,28 +167,101 @@ func Close(ctx context.Context) error {
 	return nil
 }
 
-// Register 允许用户注册新的指标
-func Register(ctx context.Context, info metric_info.MetricInfo) {
+// Register 允许用户注册新的指标，重复注册相同定义的指标是幂等的（沿用最先注册的 LabelHandler 等函数，可通过 Info 取得），
+// 定义冲突时返回 ErrAlreadyRegistered，设置了 LabelHandler 或 LabelNormalizer 的标签不同也视为冲突
+func Register(ctx context.Context, info metric_info.MetricInfo) error {
 	if r == nil {
 		panic("[metrics] metrics not initialized, call Init() first")
 	}
-	r.Register(info)
+	if err := r.Register(info); err != nil {
+		logger.Warn(ctx, "metrics register failed",
+			field.String("name", info.Name.String()),
+			field.String("err", err.Error()),
+		)
+		return err
+	}
 	logger.Debug(ctx, "metrics registered",
 		field.String("name", info.Name.String()),
 		field.String("type", info.Type.String()),
 		field.Any("labels", info.Labels),
 	)
+	return nil
+}
+
+// Info 返回已注册指标的定义，重复注册时可以用它取得实际生效的定义（包括最先注册的 LabelHandler 等函数）
+func Info(name metric_info.MetricName) (metric_info.MetricInfo, bool) {
+	if r == nil {
+		panic("[metrics] metrics not initialized, call Init() first")
+	}
+	return r.Info(name)
+}
+
+// Unregister 注销指标，返回指标是否存在，注销后可以使用新的定义重新注册
+func Unregister(ctx context.Context, name metric_info.MetricName) bool {
+	if r == nil {
+		panic("[metrics] metrics not initialized, call Init() first")
+	}
+	ok := r.Unregister(name)
+	logger.Debug(ctx, "metrics unregistered", field.String("name", name.String()))
+	return ok
 }
 
-// Report 允许用户上报数据
-func Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) {
+// Report 允许用户上报数据，指标未注册或标签不合法时返回错误
+func Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) error {
 	if r == nil {
 		panic("metrics not initialized, call Init() first")
 	}
-	r.Report(ctx, name, labels, value)
-	logger.Debug(ctx, "metrics reported",
-		field.String("name", name.String()),
-		field.Float64("value", value),
-		field.Any("labels", labels),
-	)
+	err := r.Report(ctx, name, labels, value)
+	if debug {
+		logger.Debug(ctx, "metrics reported",
+			field.String("name", name.String()),
+			field.Float64("value", value),
+			field.Any("labels", labels),
+		)
+	}
+	return err
+}
+
+// Add 对计数器或仪表累加 delta（仪表可以为负数），适用于进行中请求数等需要增减的仪表；
+// 直方图与摘要返回 ErrUnsupportedOperation
+func Add(ctx context.Context, name metric_info.MetricName, labels map[string]string, delta float64) error {
+	if r == nil {
+		panic("metrics not initialized, call Init() first")
+	}
+	err := r.Add(ctx, name, labels, delta)
+	if debug {
+		logger.Debug(ctx, "metrics added",
+			field.String("name", name.String()),
+			field.Float64("delta", delta),
+			field.Any("labels", labels),
+		)
+	}
+	return err
+}
+
+// DeleteLabelValues 删除指定标签值（按照 MetricInfo.Labels 的声明顺序）对应的时间序列
+func DeleteLabelValues(name metric_info.MetricName, values ...string) (bool, error) {
+	if r == nil {
+		panic("metrics not initialized, call Init() first")
+	}
+	return r.DeleteLabelValues(name, values...)
+}
+
+// DeletePartialMatch 删除包含指定标签的所有时间序列，返回删除的数量
+func DeletePartialMatch(name metric_info.MetricName, labels map[string]string) (int, error) {
+	if r == nil {
+		panic("metrics not initialized, call Init() first")
+	}
+	return r.DeletePartialMatch(name, labels)
+}
+
+// ContextWithLabels 返回携带 labels 的新上下文，上报时这些标签会自动合并到声明了同名标签的指标中，
+// 优先级高于 LabelHandler、低于 Report 时传入的标签，多次调用会逐层叠加
+func ContextWithLabels(ctx context.Context, labels map[string]string) context.Context {
+	return metric_info.ContextWithLabels(ctx, labels)
+}
+
+// LabelsFromContext 返回上下文中携带的标签
+func LabelsFromContext(ctx context.Context) map[string]string {
+	return metric_info.LabelsFromContext(ctx)
 }
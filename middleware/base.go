@@ -2,7 +2,16 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
 
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
 	"github.com/everfir/metrics-go"
 	"github.com/everfir/metrics-go/structs/metric_info"
 	"github.com/prometheus/client_golang/prometheus"
@@ -12,6 +21,11 @@ const (
 	MetricLatency    metric_info.MetricName = metric_info.MetricName("latency")
 	MetricStatusCode metric_info.MetricName = metric_info.MetricName("status_code")
 	MetricRequestCnt metric_info.MetricName = metric_info.MetricName("req_cnt")
+
+	MetricInFlight     metric_info.MetricName = metric_info.MetricName("requests_in_flight")
+	MetricRequestSize  metric_info.MetricName = metric_info.MetricName("request_size_bytes")
+	MetricResponseSize metric_info.MetricName = metric_info.MetricName("response_size_bytes")
+	MetricTTFB         metric_info.MetricName = metric_info.MetricName("time_to_first_byte")
 )
 
 var (
@@ -21,8 +35,25 @@ var (
 // BaseMetricsMiddleware 包含所有协议共用的功能
 type BaseMetricsMiddleware struct {
 	buildinMetrics map[metric_info.MetricName]*metric_info.MetricInfo
+	headerLabels   map[string]string // 标签名 -> 请求头
+	disabled       map[metric_info.MetricName]struct{}
+	skip           skipRules
+	sampling       latencySampling
+	statusLabels   statusLabels
+	requestLabels  map[string]RequestLabelHandler
+	responseLabels map[string]ResponseLabelHandler
+	prefix         string                                            // 指标名前缀，见 WithPrefix
+	server         string                                            // server 标签的值，见 WithServerLabel
+	names          map[metric_info.MetricName]metric_info.MetricName // 内置指标名 -> 加上前缀后的指标名
+	slo            sloConfig
+
+	initOnce sync.Once
+	initErr  error
 }
 
+// Option 配置一个中间件，供 NewHTTPMiddleware 等返回错误的构造函数使用
+type Option func(m *BaseMetricsMiddleware)
+
 // NewBaseMetricsMiddleware 创建一个新的 BaseMetricsMiddleware
 func NewBaseMetricsMiddleware() (ret *BaseMetricsMiddleware) {
 	ret = &BaseMetricsMiddleware{
@@ -35,6 +66,8 @@ func NewBaseMetricsMiddleware() (ret *BaseMetricsMiddleware) {
 		Help:         "请求总数",
 		Labels:       []string{"method"},
 		LabelHandler: map[string]metric_info.LabelHandler{},
+		// 保留已有的指标名，避免已有的看板与告警失效
+		LegacyCounterName: true,
 	}
 
 	ret.buildinMetrics[MetricLatency] = &metric_info.MetricInfo{
@@ -47,9 +80,45 @@ func NewBaseMetricsMiddleware() (ret *BaseMetricsMiddleware) {
 	}
 
 	ret.buildinMetrics[MetricStatusCode] = &metric_info.MetricInfo{
-		Type:         metric_info.Counter,
-		Name:         MetricStatusCode,
-		Help:         "响应状态码",
+		Type:              metric_info.Counter,
+		Name:              MetricStatusCode,
+		Help:              "响应状态码",
+		Labels:            []string{"method", "status", "error"},
+		LabelHandler:      map[string]metric_info.LabelHandler{},
+		LegacyCounterName: true,
+	}
+
+	ret.buildinMetrics[MetricTTFB] = &metric_info.MetricInfo{
+		Type:         metric_info.Histogram,
+		Name:         MetricTTFB,
+		Help:         "首字节时间",
+		Buckets:      prometheus.ExponentialBucketsRange(1000, 5000000, 12), // 上报单位为微秒，1ms 到 5s
+		Labels:       []string{"method", "status"},
+		LabelHandler: map[string]metric_info.LabelHandler{},
+	}
+
+	ret.buildinMetrics[MetricInFlight] = &metric_info.MetricInfo{
+		Type:         metric_info.Gauge,
+		Name:         MetricInFlight,
+		Help:         "正在处理的请求数",
+		Labels:       []string{"method"},
+		LabelHandler: map[string]metric_info.LabelHandler{},
+	}
+
+	ret.buildinMetrics[MetricRequestSize] = &metric_info.MetricInfo{
+		Type:         metric_info.Histogram,
+		Name:         MetricRequestSize,
+		Help:         "请求体大小",
+		Buckets:      prometheus.ExponentialBuckets(100, 10, 7), // 100B 到 100MB
+		Labels:       []string{"method", "status"},
+		LabelHandler: map[string]metric_info.LabelHandler{},
+	}
+
+	ret.buildinMetrics[MetricResponseSize] = &metric_info.MetricInfo{
+		Type:         metric_info.Histogram,
+		Name:         MetricResponseSize,
+		Help:         "响应体大小",
+		Buckets:      prometheus.ExponentialBuckets(100, 10, 7), // 100B 到 100MB
 		Labels:       []string{"method", "status"},
 		LabelHandler: map[string]metric_info.LabelHandler{},
 	}
@@ -67,6 +136,120 @@ func (b *BaseMetricsMiddleware) WithLabelHandler(label string, handler metric_in
 	}
 }
 
+// WithHeaderLabel 将请求头 header 的值以 label 为名写入请求上下文（见 metrics.ContextWithLabels），
+// 后续处理器上报的、声明了该标签的指标会自动带上它；Gin 中需要使用 c.Request.Context() 上报，或者开启 ContextWithFallback
+func (b *BaseMetricsMiddleware) WithHeaderLabel(header, label string) {
+	if b.headerLabels == nil {
+		b.headerLabels = make(map[string]string)
+	}
+	b.headerLabels[label] = header
+}
+
+// WithPrefix 为所有内置指标的名称加上前缀（如 admin_req_cnt），用于同一进程中的多个服务配置不同的标签、
+// 分桶等导致指标定义不一致时；需要在添加完所有指标之后、Init 之前调用
+func (b *BaseMetricsMiddleware) WithPrefix(prefix string) {
+	b.prefix = strings.TrimSuffix(prefix, "_")
+}
+
+// WithServerLabel 为所有内置指标添加值为 server 的 server 标签，定义相同的多个中间件（如公开服务与管理服务）
+// 可以共用同一组指标并以此区分；需要在添加完所有指标之后调用
+func (b *BaseMetricsMiddleware) WithServerLabel(server string) {
+	for _, info := range b.buildinMetrics {
+		declareLabel(info, "server")
+	}
+	b.server = server
+}
+
+// metricName 返回内置指标实际注册的名称
+func (b *BaseMetricsMiddleware) metricName(name metric_info.MetricName) metric_info.MetricName {
+	if b.prefix == "" {
+		return name
+	}
+	if full, ok := b.names[name]; ok {
+		return full
+	}
+	return metric_info.MetricName(b.prefix + "_" + name.String())
+}
+
+// contextWithRequestLabels 根据 WithHeaderLabel 与 WithRequestLabelHandler 的配置计算标签并写入请求上下文，
+// 请求头为空时忽略；同时返回 RequestLabelHandler 计算的标签，供内置指标直接使用
+// （Gin 使用 *gin.Context 上报，未开启 ContextWithFallback 时读不到请求上下文中的标签）
+func (b *BaseMetricsMiddleware) contextWithRequestLabels(r *http.Request) (context.Context, map[string]string) {
+	ctx := r.Context()
+	if len(b.headerLabels) == 0 && len(b.requestLabels) == 0 {
+		return ctx, nil
+	}
+	labels := make(map[string]string, len(b.headerLabels)+len(b.requestLabels))
+	for label, name := range b.headerLabels {
+		if value := r.Header.Get(name); value != "" {
+			labels[label] = value
+		}
+	}
+	var requestLabels map[string]string
+	if len(b.requestLabels) > 0 {
+		requestLabels = make(map[string]string, len(b.requestLabels))
+		for label, handler := range b.requestLabels {
+			requestLabels[label] = handler(r)
+			labels[label] = requestLabels[label]
+		}
+	}
+	return metrics.ContextWithLabels(ctx, labels), requestLabels
+}
+
+// EnableMetric 开启或关闭一个内置指标，关闭后既不会注册也不会上报，仅能在初始化时调用
+func (b *BaseMetricsMiddleware) EnableMetric(name metric_info.MetricName, enabled bool) {
+	if enabled {
+		delete(b.disabled, name)
+		return
+	}
+	if b.disabled == nil {
+		b.disabled = make(map[metric_info.MetricName]struct{})
+	}
+	b.disabled[name] = struct{}{}
+}
+
+func (b *BaseMetricsMiddleware) enabled(name metric_info.MetricName) bool {
+	_, disabled := b.disabled[name]
+	return !disabled
+}
+
+// report 上报一个未被关闭的内置指标
+func (b *BaseMetricsMiddleware) report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) {
+	if b.enabled(name) {
+		metrics.Report(ctx, b.metricName(name), labels, value)
+	}
+}
+
+// add 对一个未被关闭的内置指标累加 delta
+func (b *BaseMetricsMiddleware) add(ctx context.Context, name metric_info.MetricName, labels map[string]string, delta float64) {
+	if b.enabled(name) {
+		metrics.Add(ctx, b.metricName(name), labels, delta)
+	}
+}
+
+// bodyCounter 统计处理器实际读取的请求体大小
+type bodyCounter struct {
+	io.ReadCloser
+	size int64
+}
+
+func (c *bodyCounter) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.size += int64(n)
+	return n, err
+}
+
+// CountRequestBody 返回获取请求体大小的函数，供各框架的适配器使用，ContentLength 未知（如分块传输）时统计处理器实际读取的字节数
+func CountRequestBody(r *http.Request) func() int64 {
+	if r.ContentLength >= 0 || r.Body == nil || r.Body == http.NoBody {
+		size := max(r.ContentLength, 0)
+		return func() int64 { return size }
+	}
+	counter := &bodyCounter{ReadCloser: r.Body}
+	r.Body = counter
+	return func() int64 { return counter.size }
+}
+
 func (b *BaseMetricsMiddleware) WithMetric(info *metric_info.MetricInfo) {
 	b.buildinMetrics[info.Name] = info
 }
@@ -84,13 +267,77 @@ func (b *BaseMetricsMiddleware) clone() *BaseMetricsMiddleware {
 		buildinMetrics: make(map[metric_info.MetricName]*metric_info.MetricInfo),
 	}
 	for name, info := range b.buildinMetrics {
-		clone.buildinMetrics[name] = info
+		clone.buildinMetrics[name] = info.Clone()
+	}
+	for label, header := range b.headerLabels {
+		clone.WithHeaderLabel(header, label)
+	}
+	for name := range b.disabled {
+		clone.EnableMetric(name, false)
 	}
+	clone.skip = b.skip.clone()
+	clone.sampling = b.sampling.clone()
+	clone.statusLabels = b.statusLabels
+	clone.requestLabels = maps.Clone(b.requestLabels)
+	clone.responseLabels = maps.Clone(b.responseLabels)
+	clone.prefix = b.prefix
+	clone.server = b.server
+	clone.slo.objectives = slices.Clone(b.slo.objectives)
+	clone.slo.windows = slices.Clone(b.slo.windows)
 	return clone
 }
 
-func (b *BaseMetricsMiddleware) Init(ctx context.Context) {
+// Init 注册所有未被关闭的内置指标，只会执行一次，之后的调用返回第一次的结果；Middleware 会自动调用，
+// 需要在此之前完成配置。多个定义相同的中间件共用同一组指标是安全的，
+// 与已注册的同名指标定义不一致时返回错误，此时需要通过 WithPrefix 区分
+func (b *BaseMetricsMiddleware) Init(ctx context.Context) error {
+	b.initOnce.Do(func() {
+		b.initErr = b.register(ctx)
+	})
+	return b.initErr
+}
+
+// LazyInit 供各框架的 Middleware 调用，执行 Init 并在失败时记录警告日志；
+// Middleware 无法返回错误，需要在启动时感知失败的应当使用返回错误的构造函数或显式调用 Init
+func (b *BaseMetricsMiddleware) LazyInit(ctx context.Context) {
+	if err := b.Init(ctx); err != nil {
+		logger.Warn(ctx, "metrics middleware init failed", field.String("err", err.Error()))
+	}
+}
+
+// Setup 依次应用 opts 并注册指标，供各框架返回错误的构造函数使用
+func (b *BaseMetricsMiddleware) Setup(ctx context.Context, opts ...Option) error {
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b.Init(ctx)
+}
+
+func (b *BaseMetricsMiddleware) register(ctx context.Context) error {
+	if err := b.validateSLO(); err != nil {
+		return err
+	}
+
+	var errs []error
+	names := make(map[metric_info.MetricName]metric_info.MetricName, len(b.buildinMetrics))
+	register := func(info *metric_info.MetricInfo) {
+		if !b.enabled(info.Name) {
+			return
+		}
+		names[info.Name] = b.metricName(info.Name)
+		registered := *info
+		registered.Name = names[info.Name]
+		if err := metrics.Register(ctx, registered); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	for _, info := range b.buildinMetrics {
-		metrics.Register(ctx, *info)
+		register(info)
+	}
+	for _, info := range b.sloMetrics() {
+		register(info)
 	}
+	b.names = names
+	b.initSLO(ctx)
+	return errors.Join(errs...)
 }
@@ -0,0 +1,45 @@
+// Package chimetrics 提供 Chi 框架的指标中间件，使用 Chi 的路由模板作为 method 标签
+package chimetrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/everfir/metrics-go/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// ChiMetricsMiddleware 是针对 Chi 框架的指标中间件
+type ChiMetricsMiddleware struct {
+	*middleware.BaseMetricsMiddleware
+}
+
+// ChiMiddleware 创建一个新的 Chi 指标中间件
+func ChiMiddleware() *ChiMetricsMiddleware {
+	return &ChiMetricsMiddleware{
+		BaseMetricsMiddleware: middleware.NewBaseMetricsMiddleware(),
+	}
+}
+
+// NewChiMiddleware 创建一个新的 Chi 指标中间件，应用 opts 后立即注册指标，注册失败时返回错误
+func NewChiMiddleware(ctx context.Context, opts ...middleware.Option) (*ChiMetricsMiddleware, error) {
+	m := ChiMiddleware()
+	if err := m.Setup(ctx, opts...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Middleware 返回一个适用于 Chi 框架的中间件函数，可以直接传给 Router.Use，尚未调用 Init 时会自动注册指标
+func (m *ChiMetricsMiddleware) Middleware(next http.Handler) http.Handler {
+	m.LazyInit(context.Background())
+	return m.WrapHandler(next, routePattern)
+}
+
+// routePattern 返回 Chi 匹配到的路由模板（如 /users/{id}），只有在路由完成后才能取得
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+// Package echometrics 提供 Echo 框架的指标中间件，使用 Echo 的路由模板作为 method 标签
+package echometrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/everfir/metrics-go/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// EchoMetricsMiddleware 是针对 Echo 框架的指标中间件
+type EchoMetricsMiddleware struct {
+	*middleware.BaseMetricsMiddleware
+}
+
+// EchoMiddleware 创建一个新的 Echo 指标中间件
+func EchoMiddleware() *EchoMetricsMiddleware {
+	return &EchoMetricsMiddleware{
+		BaseMetricsMiddleware: middleware.NewBaseMetricsMiddleware(),
+	}
+}
+
+// NewEchoMiddleware 创建一个新的 Echo 指标中间件，应用 opts 后立即注册指标，注册失败时返回错误
+func NewEchoMiddleware(ctx context.Context, opts ...middleware.Option) (*EchoMetricsMiddleware, error) {
+	m := EchoMiddleware()
+	if err := m.Setup(ctx, opts...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Middleware 返回一个适用于 Echo 框架的中间件函数，需要通过 Echo.Use 注册以便在路由之后执行，尚未调用 Init 时会自动注册指标
+func (m *EchoMetricsMiddleware) Middleware() echo.MiddlewareFunc {
+	m.LazyInit(context.Background())
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if m.Skip(c.Request()) {
+				return next(c)
+			}
+
+			o, r := m.Begin(c.Request().Context(), c.Request(), c.Path())
+			defer o.Done()
+			c.SetRequest(r)
+			requestSize := middleware.CountRequestBody(r)
+
+			// 在写出状态码之前记录首字节时间
+			var ttfb time.Duration
+			c.Response().Before(func() {
+				ttfb = time.Since(o.Start())
+			})
+
+			// 调用下一个处理器
+			err := next(c)
+
+			o.End(middleware.Result{
+				Status:       status(c, err),
+				RequestSize:  requestSize(),
+				ResponseSize: c.Response().Size,
+				TTFB:         ttfb,
+			})
+			return err
+		}
+	}
+}
+
+// status 返回响应状态码，处理器返回错误且尚未写出响应时，响应由之后的 HTTPErrorHandler 写出，需要根据错误推断
+func status(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	var httpError *echo.HTTPError
+	if errors.As(err, &httpError) {
+		return httpError.Code
+	}
+	return http.StatusInternalServerError
+}
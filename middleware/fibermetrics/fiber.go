@@ -0,0 +1,92 @@
+// Package fibermetrics 提供 Fiber 框架的指标中间件，使用 Fiber 的路由模板作为 method 标签
+package fibermetrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/everfir/metrics-go/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// FiberMetricsMiddleware 是针对 Fiber 框架的指标中间件
+type FiberMetricsMiddleware struct {
+	*middleware.BaseMetricsMiddleware
+}
+
+// FiberMiddleware 创建一个新的 Fiber 指标中间件
+func FiberMiddleware() *FiberMetricsMiddleware {
+	return &FiberMetricsMiddleware{
+		BaseMetricsMiddleware: middleware.NewBaseMetricsMiddleware(),
+	}
+}
+
+// NewFiberMiddleware 创建一个新的 Fiber 指标中间件，应用 opts 后立即注册指标，注册失败时返回错误
+func NewFiberMiddleware(ctx context.Context, opts ...middleware.Option) (*FiberMetricsMiddleware, error) {
+	m := FiberMiddleware()
+	if err := m.Setup(ctx, opts...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Middleware 返回一个适用于 Fiber 框架的中间件函数；Fiber 的路由要到处理完成后才能确定，
+// 因此 requests_in_flight 不区分路由，首字节时间也无法获取；尚未调用 Init 时会自动注册指标
+func (m *FiberMetricsMiddleware) Middleware() fiber.Handler {
+	m.LazyInit(context.Background())
+	return func(c *fiber.Ctx) error {
+		r, err := m.request(c)
+		if err != nil || m.Skip(r) {
+			return c.Next()
+		}
+
+		o, r := m.Begin(r.Context(), r, "")
+		defer o.Done()
+		c.SetUserContext(r.Context())
+
+		// 调用下一个处理器
+		err = c.Next()
+
+		o.End(middleware.Result{
+			Status:       status(c, err),
+			Route:        c.Route().Path,
+			RequestSize:  int64(len(c.Request().Body())),
+			ResponseSize: int64(len(c.Response().Body())),
+		})
+		return err
+	}
+}
+
+// request 构造标签处理函数等使用的 *http.Request，只有在需要时才完整转换请求
+func (m *FiberMetricsMiddleware) request(c *fiber.Ctx) (*http.Request, error) {
+	if m.NeedsRequest() {
+		r, err := adaptor.ConvertRequest(c, false)
+		if err != nil {
+			return nil, err
+		}
+		return r.WithContext(c.UserContext()), nil
+	}
+	// fasthttp 会复用请求的内存，需要复制
+	r := &http.Request{
+		Method: utils.CopyString(c.Method()),
+		URL:    &url.URL{Path: utils.CopyString(c.Path())},
+		Header: http.Header{},
+	}
+	return r.WithContext(c.UserContext()), nil
+}
+
+// status 返回响应状态码，处理器返回错误时响应由之后的 ErrorHandler 写出，需要根据错误推断
+func status(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fiberError *fiber.Error
+	if errors.As(err, &fiberError) {
+		return fiberError.Code
+	}
+	return http.StatusInternalServerError
+}
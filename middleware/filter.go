@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// skipRules 决定哪些请求完全不记录指标，如健康检查与 /metrics 本身
+type skipRules struct {
+	paths    map[string]struct{}
+	prefixes []string
+	regexps  []*regexp.Regexp
+	funcs    []func(r *http.Request) bool
+}
+
+func (s *skipRules) match(r *http.Request) bool {
+	path := r.URL.Path
+	if _, ok := s.paths[path]; ok {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	for _, re := range s.regexps {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	for _, fn := range s.funcs {
+		if fn(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s skipRules) clone() skipRules {
+	return skipRules{
+		paths:    maps.Clone(s.paths),
+		prefixes: slices.Clone(s.prefixes),
+		regexps:  slices.Clone(s.regexps),
+		funcs:    slices.Clone(s.funcs),
+	}
+}
+
+// latencySampling 是时延类直方图的采样率，未设置时全部记录
+type latencySampling struct {
+	rate  float64            // 默认采样率，0 表示未设置
+	paths map[string]float64 // 按路径设置的采样率，优先于默认采样率
+}
+
+// sampled 判断本次请求的时延是否需要记录
+func (s *latencySampling) sampled(path string) bool {
+	rate, ok := s.paths[path]
+	if !ok {
+		rate = s.rate
+	}
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
+}
+
+func (s latencySampling) clone() latencySampling {
+	return latencySampling{rate: s.rate, paths: maps.Clone(s.paths)}
+}
+
+// WithSkipPaths 跳过路径与 paths 完全一致的请求，这些请求不会记录任何指标
+func (b *BaseMetricsMiddleware) WithSkipPaths(paths ...string) {
+	if b.skip.paths == nil {
+		b.skip.paths = make(map[string]struct{}, len(paths))
+	}
+	for _, path := range paths {
+		b.skip.paths[path] = struct{}{}
+	}
+}
+
+// WithSkipPrefixes 跳过路径以 prefixes 中任意一个开头的请求
+func (b *BaseMetricsMiddleware) WithSkipPrefixes(prefixes ...string) {
+	b.skip.prefixes = append(b.skip.prefixes, prefixes...)
+}
+
+// WithSkipRegexps 跳过路径匹配 regexps 中任意一个的请求
+func (b *BaseMetricsMiddleware) WithSkipRegexps(regexps ...*regexp.Regexp) {
+	b.skip.regexps = append(b.skip.regexps, regexps...)
+}
+
+// WithSkipper 跳过 skipper 返回 true 的请求，可以根据请求头、方法等任意条件判断
+func (b *BaseMetricsMiddleware) WithSkipper(skipper func(r *http.Request) bool) {
+	b.skip.funcs = append(b.skip.funcs, skipper)
+}
+
+// WithLatencySampling 设置时延类直方图（latency 与 time_to_first_byte）的采样率，取值 (0, 1)，
+// 指定 paths 时只对这些路径生效，否则作为所有路径的默认采样率；请求数等其他指标不受影响
+func (b *BaseMetricsMiddleware) WithLatencySampling(rate float64, paths ...string) {
+	if len(paths) == 0 {
+		b.sampling.rate = rate
+		return
+	}
+	if b.sampling.paths == nil {
+		b.sampling.paths = make(map[string]float64, len(paths))
+	}
+	for _, path := range paths {
+		b.sampling.paths[path] = rate
+	}
+}
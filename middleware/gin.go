@@ -1,13 +1,20 @@
 package middleware
 
 import (
-	"strconv"
-	"time"
+	"bufio"
+	"context"
+	"net"
+	"net/http"
 
-	"github.com/everfir/metrics-go"
+	"github.com/everfir/metrics-go/structs/metric_info"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	MetricPanics metric_info.MetricName = metric_info.MetricName("panics_total")
+	MetricErrors metric_info.MetricName = metric_info.MetricName("errors_total")
+)
+
 // GinMetricsMiddleware 是针对 Gin 框架的指标中间件
 type GinMetricsMiddleware struct {
 	*BaseMetricsMiddleware
@@ -18,27 +25,138 @@ func GinMiddleware() *GinMetricsMiddleware {
 	m := &GinMetricsMiddleware{
 		BaseMetricsMiddleware: defaultBaseMiddleware.clone(),
 	}
+	m.WithMetric(&metric_info.MetricInfo{
+		Type:         metric_info.Counter,
+		Name:         MetricPanics,
+		Help:         "处理器 panic 次数",
+		Labels:       []string{"route"},
+		LabelHandler: map[string]metric_info.LabelHandler{},
+	})
+	m.WithMetric(&metric_info.MetricInfo{
+		Type:         metric_info.Counter,
+		Name:         MetricErrors,
+		Help:         "处理器通过 c.Error 记录的错误数",
+		Labels:       []string{"method", "type"},
+		LabelHandler: map[string]metric_info.LabelHandler{},
+	})
 	return m
 }
 
-// Middleware 返回一个适用于 Gin 框架的中间件函数
+// NewGinMiddleware 创建一个新的 Gin 指标中间件，应用 opts 后立即注册指标，注册失败时返回错误
+func NewGinMiddleware(ctx context.Context, opts ...Option) (*GinMetricsMiddleware, error) {
+	m := GinMiddleware()
+	if err := m.Setup(ctx, opts...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Middleware 返回一个适用于 Gin 框架的中间件函数，尚未调用 Init 时会自动注册指标
 func (m *GinMetricsMiddleware) Middleware() gin.HandlerFunc {
+	m.LazyInit(context.Background())
 	return func(c *gin.Context) {
-		start := time.Now()
-
-		labels := map[string]string{
-			"method": c.Request.URL.Path,
+		if m.Skip(c.Request) {
+			c.Next()
+			return
 		}
 
-		// 记录请求
-		metrics.Report(c, MetricRequestCnt, labels, 1)
+		o, r := m.Begin(c, c.Request, c.Request.URL.Path)
+		defer o.Done()
+		c.Request = r
+		requestSize := CountRequestBody(c.Request)
+
+		// 包装 ResponseWriter 以捕获首字节时间
+		writer := &ginResponseWriter{ResponseWriter: c.Writer, firstByte: firstByte{start: o.Start()}}
+		c.Writer = writer
+
+		// 记录 panic 后继续向上抛出，交给 gin.Recovery 等处理
+		defer func() {
+			if err := recover(); err != nil {
+				if err != http.ErrAbortHandler {
+					m.report(c, MetricPanics, mergeLabels(o.requestLabels, map[string]string{"route": c.FullPath()}), 1)
+				}
+				panic(err)
+			}
+		}()
 
 		// 调用下一个处理器
 		c.Next()
 
 		// 记录指标
-		labels["status"] = strconv.Itoa(c.Writer.Status())
-		metrics.Report(c, MetricStatusCode, labels, 1)
-		metrics.Report(c, MetricLatency, labels, float64(time.Since(start).Microseconds()))
+		for _, err := range c.Errors {
+			m.report(c, MetricErrors, mergeLabels(o.Labels(), map[string]string{"type": ginErrorType(err.Type)}), 1)
+		}
+		o.End(Result{
+			Status:       writer.status(),
+			RequestSize:  requestSize(),
+			ResponseSize: int64(max(c.Writer.Size(), 0)),
+			TTFB:         writer.firstByte.elapsed,
+		})
+	}
+}
+
+// ginResponseWriter 在 gin.ResponseWriter 的基础上记录首字节时间，
+// gin 的状态码是延迟写出的，因此只在真正写出响应时记录
+type ginResponseWriter struct {
+	gin.ResponseWriter
+	firstByte firstByte
+	hijacked  bool
+}
+
+// Hijack 成功后 gin 不再知道实际写出的状态码，按照协议升级记录
+func (w *ginResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := w.ResponseWriter.Hijack()
+	if err == nil {
+		w.firstByte.mark()
+		w.hijacked = true
+	}
+	return conn, buf, err
+}
+
+func (w *ginResponseWriter) status() int {
+	if w.hijacked {
+		return http.StatusSwitchingProtocols
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *ginResponseWriter) WriteHeaderNow() {
+	w.firstByte.mark()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *ginResponseWriter) Write(data []byte) (int, error) {
+	w.firstByte.mark()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *ginResponseWriter) WriteString(s string) (int, error) {
+	w.firstByte.mark()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *ginResponseWriter) Flush() {
+	w.firstByte.mark()
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap 返回被包装的 ResponseWriter，供 http.ResponseController 使用
+func (w *ginResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ginErrorType 返回 gin.ErrorType 对应的 type 标签值
+func ginErrorType(t gin.ErrorType) string {
+	switch {
+	case t&gin.ErrorTypeBind != 0:
+		return "bind"
+	case t&gin.ErrorTypeRender != 0:
+		return "render"
+	case t&gin.ErrorTypePublic != 0:
+		return "public"
+	case t&gin.ErrorTypePrivate != 0:
+		return "private"
+	default:
+		return "other"
 	}
 }
@@ -0,0 +1,76 @@
+// Package hertzmetrics 提供 Hertz 框架的指标中间件，使用 Hertz 的路由模板作为 method 标签。
+// 为了避免根模块依赖 Hertz，本包是一个独立的模块
+package hertzmetrics
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/adaptor"
+	"github.com/everfir/metrics-go/middleware"
+)
+
+// HertzMetricsMiddleware 是针对 Hertz 框架的指标中间件
+type HertzMetricsMiddleware struct {
+	*middleware.BaseMetricsMiddleware
+}
+
+// HertzMiddleware 创建一个新的 Hertz 指标中间件
+func HertzMiddleware() *HertzMetricsMiddleware {
+	return &HertzMetricsMiddleware{
+		BaseMetricsMiddleware: middleware.NewBaseMetricsMiddleware(),
+	}
+}
+
+// NewHertzMiddleware 创建一个新的 Hertz 指标中间件，应用 opts 后立即注册指标，注册失败时返回错误
+func NewHertzMiddleware(ctx context.Context, opts ...middleware.Option) (*HertzMetricsMiddleware, error) {
+	m := HertzMiddleware()
+	if err := m.Setup(ctx, opts...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Middleware 返回一个适用于 Hertz 框架的中间件函数，首字节时间无法获取；尚未调用 Init 时会自动注册指标
+func (m *HertzMetricsMiddleware) Middleware() app.HandlerFunc {
+	m.LazyInit(context.Background())
+	return func(ctx context.Context, c *app.RequestContext) {
+		r, err := m.request(ctx, c)
+		if err != nil || m.Skip(r) {
+			c.Next(ctx)
+			return
+		}
+
+		o, r := m.Begin(ctx, r, c.FullPath())
+		defer o.Done()
+
+		// 调用下一个处理器，上下文中带有 WithHeaderLabel 等写入的标签
+		c.Next(r.Context())
+
+		o.End(middleware.Result{
+			Status:       c.Response.StatusCode(),
+			RequestSize:  int64(len(c.Request.Body())),
+			ResponseSize: int64(len(c.Response.Body())),
+		})
+	}
+}
+
+// request 构造标签处理函数等使用的 *http.Request，只有在需要时才完整转换请求
+func (m *HertzMetricsMiddleware) request(ctx context.Context, c *app.RequestContext) (*http.Request, error) {
+	if m.NeedsRequest() {
+		r, err := adaptor.GetCompatRequest(&c.Request)
+		if err != nil {
+			return nil, err
+		}
+		return r.WithContext(ctx), nil
+	}
+	// Hertz 会复用请求的内存，需要复制
+	r := &http.Request{
+		Method: string(c.Method()),
+		URL:    &url.URL{Path: string(c.Path())},
+		Header: http.Header{},
+	}
+	return r.WithContext(ctx), nil
+}
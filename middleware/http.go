@@ -1,11 +1,8 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
-	"strconv"
-	"time"
-
-	"github.com/everfir/metrics-go"
 )
 
 // NetHTTPMetricsMiddleware 是针对 HTTP 协议的指标中间件
@@ -21,46 +18,17 @@ func HTTPMiddleware() *NetHTTPMetricsMiddleware {
 	return m
 }
 
-// Middleware 返回一个适用于标准 net/http 的中间件函数
-func (m *NetHTTPMetricsMiddleware) Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-
-		labels := map[string]string{
-			"method": r.URL.Path,
-		}
-
-		// 记录请求
-		ctx := r.Context()
-		metrics.Report(ctx, MetricRequestCnt, labels, 1)
-
-		// 包装 ResponseWriter 以捕获状态码和响应大小
-		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-
-		// 调用下一个处理器
-		next.ServeHTTP(rw, r)
-
-		// 响应时间和状态码
-		labels["status"] = strconv.Itoa(rw.statusCode)
-		metrics.Report(ctx, MetricStatusCode, labels, 1)
-		metrics.Report(ctx, MetricLatency, labels, float64(time.Since(start).Microseconds()))
-	})
-}
-
-// responseWriter 是一个包装了 http.ResponseWriter 的结构体，用于捕获状态码和响应大小
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-	size       int
-}
-
-func (rw *responseWriter) WriteHeader(statusCode int) {
-	rw.statusCode = statusCode
-	rw.ResponseWriter.WriteHeader(statusCode)
+// NewHTTPMiddleware 创建一个新的 HTTP 指标中间件，应用 opts 后立即注册指标，注册失败时返回错误
+func NewHTTPMiddleware(ctx context.Context, opts ...Option) (*NetHTTPMetricsMiddleware, error) {
+	m := HTTPMiddleware()
+	if err := m.Setup(ctx, opts...); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func (rw *responseWriter) Write(b []byte) (int, error) {
-	size, err := rw.ResponseWriter.Write(b)
-	rw.size += size
-	return size, err
+// Middleware 返回一个适用于标准 net/http 的中间件函数，尚未调用 Init 时会自动注册指标
+func (m *NetHTTPMetricsMiddleware) Middleware(next http.Handler) http.Handler {
+	m.LazyInit(context.Background())
+	return m.WrapHandler(next, nil)
 }
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/everfir/metrics-go/structs/metric_info"
+)
+
+// RequestLabelHandler 根据请求计算标签值（如请求头、Host、User-Agent 类别、API 版本），在调用下一个处理器之前求值
+type RequestLabelHandler func(r *http.Request) string
+
+// ResponseLabelHandler 根据请求与响应状态码计算标签值，在下一个处理器返回之后求值
+type ResponseLabelHandler func(r *http.Request, status int) string
+
+// responseMetrics 是在下一个处理器返回之后才上报的内置指标，只有它们会带上 ResponseLabelHandler 的标签
+var responseMetrics = []metric_info.MetricName{
+	MetricStatusCode,
+	MetricLatency,
+	MetricTTFB,
+	MetricRequestSize,
+	MetricResponseSize,
+}
+
+// WithRequestLabelHandler 为所有内置指标添加一个由请求计算的标签；标签值会写入请求上下文，
+// 因此后续处理器上报的、声明了该标签的指标也会自动带上它
+func (b *BaseMetricsMiddleware) WithRequestLabelHandler(label string, handler RequestLabelHandler) {
+	for _, info := range b.buildinMetrics {
+		declareLabel(info, label)
+	}
+	if b.requestLabels == nil {
+		b.requestLabels = make(map[string]RequestLabelHandler)
+	}
+	b.requestLabels[label] = handler
+}
+
+// WithResponseLabelHandler 为请求完成后上报的内置指标（status_code、latency、time_to_first_byte、
+// request_size_bytes、response_size_bytes）添加一个由请求与响应状态码计算的标签
+func (b *BaseMetricsMiddleware) WithResponseLabelHandler(label string, handler ResponseLabelHandler) {
+	for _, name := range responseMetrics {
+		if info, ok := b.buildinMetrics[name]; ok {
+			declareLabel(info, label)
+		}
+	}
+	if b.responseLabels == nil {
+		b.responseLabels = make(map[string]ResponseLabelHandler)
+	}
+	b.responseLabels[label] = handler
+}
+
+// addResponseLabels 计算 ResponseLabelHandler 的标签并写入 labels
+func (b *BaseMetricsMiddleware) addResponseLabels(labels map[string]string, r *http.Request, status int) {
+	for label, handler := range b.responseLabels {
+		labels[label] = handler(r, status)
+	}
+}
+
+func declareLabel(info *metric_info.MetricInfo, label string) {
+	if !slices.Contains(info.Labels, label) {
+		info.Labels = append(info.Labels, label)
+	}
+}
+
+// mergeLabels 返回 base 与 extra 合并后的新标签，extra 优先
+func mergeLabels(base, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(extra))
+	maps.Copy(merged, base)
+	maps.Copy(merged, extra)
+	return merged
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"time"
+)
+
+// Observation 是一次请求的指标记录，各框架的中间件在调用下一个处理器之前通过 BaseMetricsMiddleware.Begin 创建，
+// 之后需要 defer 调用 Done，并在下一个处理器正常返回后调用 End
+type Observation struct {
+	b             *BaseMetricsMiddleware
+	ctx           context.Context // 上报内置指标时使用的上下文
+	request       *http.Request
+	start         time.Time
+	route         string
+	requestLabels map[string]string // RequestLabelHandler 计算的标签以及 server 标签
+	inFlight      map[string]string
+	counted       bool // 请求数是否已经记录
+}
+
+// Result 是下一个处理器返回后各框架中间件收集到的信息
+type Result struct {
+	Status       int
+	Route        string        // 路由要到处理完成后才能确定时（如 Chi、Fiber）设置，否则为空
+	RequestSize  int64         // 请求体大小
+	ResponseSize int64         // 响应体大小
+	TTFB         time.Duration // 首字节时间，未知或未写出响应时为 0
+}
+
+// Skip 判断请求是否命中 WithSkipPaths 等跳过规则
+func (b *BaseMetricsMiddleware) Skip(r *http.Request) bool {
+	return b.skip.match(r)
+}
+
+// NeedsRequest 判断跳过规则、标签处理函数等是否需要完整的请求（请求头、Host 等），
+// 基于 fasthttp 的框架可以据此决定是否构造完整的 *http.Request，否则只需要设置 Method 与 URL.Path
+func (b *BaseMetricsMiddleware) NeedsRequest() bool {
+	return len(b.skip.funcs) > 0 || len(b.headerLabels) > 0 || len(b.requestLabels) > 0 ||
+		len(b.responseLabels) > 0 || b.statusLabels.isError != nil
+}
+
+// Begin 在调用下一个处理器之前调用：计算请求标签，记录请求数与正在处理的请求数。
+// ctx 是上报内置指标时使用的上下文（LabelHandler 收到的即为它），route 作为 method 标签，
+// 为空表示路由要到处理完成后才能确定，此时请求数在 End 中记录，requests_in_flight 不区分路由。
+// 返回的请求带有写入了标签的上下文，需要传给下一个处理器
+func (b *BaseMetricsMiddleware) Begin(ctx context.Context, r *http.Request, route string) (*Observation, *http.Request) {
+	requestCtx, requestLabels := b.contextWithRequestLabels(r)
+	r = r.WithContext(requestCtx)
+	if b.server != "" {
+		requestLabels = mergeLabels(requestLabels, map[string]string{"server": b.server})
+	}
+
+	o := &Observation{
+		b:             b,
+		ctx:           ctx,
+		request:       r,
+		start:         time.Now(),
+		route:         route,
+		requestLabels: requestLabels,
+	}
+	labels := o.labels()
+	if route != "" {
+		b.report(ctx, MetricRequestCnt, labels, 1)
+		o.counted = true
+	}
+	o.inFlight = labels
+	b.add(ctx, MetricInFlight, o.inFlight, 1)
+	return o, r
+}
+
+// Start 返回请求开始的时间
+func (o *Observation) Start() time.Time {
+	return o.start
+}
+
+// Done 将正在处理的请求数减一，需要使用 defer 调用以保证处理器 panic 时也能执行
+func (o *Observation) Done() {
+	o.b.add(o.ctx, MetricInFlight, o.inFlight, -1)
+}
+
+// End 在下一个处理器返回之后调用，记录状态码、时延、请求与响应大小以及 SLO
+func (o *Observation) End(res Result) {
+	b := o.b
+	elapsed := time.Since(o.start)
+	if res.Route != "" {
+		o.route = res.Route
+	}
+	labels := o.labels()
+	if !o.counted {
+		b.report(o.ctx, MetricRequestCnt, labels, 1)
+		o.counted = true
+	}
+
+	labels["status"] = b.statusLabels.status(res.Status)
+	b.addResponseLabels(labels, o.request, res.Status)
+	b.report(o.ctx, MetricStatusCode, mergeLabels(labels, map[string]string{"error": b.statusLabels.error(o.request, res.Status)}), 1)
+	b.report(o.ctx, MetricRequestSize, labels, float64(res.RequestSize))
+	b.report(o.ctx, MetricResponseSize, labels, float64(res.ResponseSize))
+	if b.sampling.sampled(o.request.URL.Path) {
+		b.report(o.ctx, MetricLatency, labels, float64(elapsed.Microseconds()))
+		if res.TTFB > 0 {
+			b.report(o.ctx, MetricTTFB, labels, float64(res.TTFB.Microseconds()))
+		}
+	}
+	b.recordSLO(o, res.Status, elapsed)
+}
+
+// Labels 返回 method、server 标签以及 RequestLabelHandler 计算的标签，框架特有的指标可以在此基础上添加标签
+func (o *Observation) Labels() map[string]string {
+	return o.labels()
+}
+
+func (o *Observation) labels() map[string]string {
+	labels := make(map[string]string, len(o.requestLabels)+3)
+	maps.Copy(labels, o.requestLabels)
+	labels["method"] = o.route
+	return labels
+}
+
+// WrapHandler 返回记录 next 指标的 net/http 处理器，供基于 net/http 的框架适配器使用；
+// routeOf 为空时使用请求路径作为 method 标签，否则在处理完成后通过 routeOf 获取路由模板
+func (b *BaseMetricsMiddleware) WrapHandler(next http.Handler, routeOf func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.Skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := ""
+		if routeOf == nil {
+			route = r.URL.Path
+		}
+		o, r := b.Begin(r.Context(), r, route)
+		defer o.Done()
+		requestSize := CountRequestBody(r)
+
+		// 包装 ResponseWriter 以捕获状态码、响应大小和首字节时间
+		rw, delegate := newResponseWriter(w, o.Start())
+
+		// 调用下一个处理器
+		next.ServeHTTP(delegate, r)
+
+		res := Result{
+			Status:       rw.statusCode,
+			RequestSize:  requestSize(),
+			ResponseSize: int64(rw.size),
+			TTFB:         rw.firstByte.elapsed,
+		}
+		if routeOf != nil {
+			res.Route = routeOf(r)
+		}
+		o.End(res)
+	})
+}
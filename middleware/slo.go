@@ -0,0 +1,304 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/everfir/metrics-go/structs/metric_info"
+)
+
+const (
+	MetricSLOEvents     metric_info.MetricName = metric_info.MetricName("slo_events_total")
+	MetricSLOGoodEvents metric_info.MetricName = metric_info.MetricName("slo_good_events_total")
+	MetricSLOObjective  metric_info.MetricName = metric_info.MetricName("slo_objective")
+	MetricSLOBurnRate   metric_info.MetricName = metric_info.MetricName("slo_burn_rate")
+)
+
+// objective 标签的取值
+const (
+	sloLatency      = "latency"
+	sloAvailability = "availability"
+)
+
+// defaultBurnRateWindows 是多窗口燃烧率告警常用的窗口：5m/1h 用于快速告警，30m/6h 用于慢速告警
+var defaultBurnRateWindows = []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour}
+
+// Objective 描述一个路由的服务等级目标，时延目标与可用性目标至少设置一个
+type Objective struct {
+	Route         string        // 路由，与内置指标 method 标签的值一致
+	Latency       time.Duration // 时延阈值，需要与 LatencyTarget 同时设置
+	LatencyTarget float64       // 时延不超过 Latency 的请求比例目标，如 0.99
+	Availability  float64       // 非错误请求（见 WithErrorClassifier）的比例目标，如 0.999，0 表示不设置
+}
+
+// sloConfig 保存 SLO 的配置以及 Init 之后的运行状态
+type sloConfig struct {
+	objectives []Objective
+	windows    []time.Duration // 为空表示不计算燃烧率
+
+	trackers  map[string][]*sloTracker // 路由 -> 目标，Init 之后只读
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// sloTracker 统计一个路由的一个目标
+type sloTracker struct {
+	objective string
+	target    float64
+	latency   time.Duration
+	labels    map[string]string // 上报 slo_events_total 等指标使用的标签，上报时只读
+	events    *eventWindow      // 未开启燃烧率时为 nil
+}
+
+// good 判断一次请求是否满足目标
+func (t *sloTracker) good(isError bool, elapsed time.Duration) bool {
+	if t.objective == sloLatency {
+		return elapsed <= t.latency
+	}
+	return !isError
+}
+
+// WithSLO 声明路由的服务等级目标，中间件会为每个目标记录 slo_events_total 与 slo_good_events_total，
+// 并通过 slo_objective 暴露目标值，便于在 PromQL 中计算错误预算；需要在 Init 之前调用
+func (b *BaseMetricsMiddleware) WithSLO(objectives ...Objective) {
+	b.slo.objectives = append(b.slo.objectives, objectives...)
+}
+
+// WithSLOBurnRate 在进程内按 windows 计算每个目标的燃烧率（错误率 / 错误预算）并通过 slo_burn_rate 暴露，
+// 用于没有配置记录规则的小型服务直接告警；windows 为空时使用 5m、30m、1h、6h。需要在 Init 之前调用
+func (b *BaseMetricsMiddleware) WithSLOBurnRate(windows ...time.Duration) {
+	if len(windows) == 0 {
+		windows = defaultBurnRateWindows
+	}
+	b.slo.windows = slices.Clone(windows)
+	slices.Sort(b.slo.windows)
+}
+
+// Close 停止燃烧率的后台计算，未开启燃烧率时无需调用
+func (b *BaseMetricsMiddleware) Close() {
+	if b.slo.done != nil {
+		b.slo.closeOnce.Do(func() { close(b.slo.done) })
+	}
+}
+
+// sloMetrics 返回 SLO 相关指标的定义，未声明目标时为空
+func (b *BaseMetricsMiddleware) sloMetrics() []*metric_info.MetricInfo {
+	if len(b.slo.objectives) == 0 {
+		return nil
+	}
+	labels := []string{"route", "objective"}
+	if b.server != "" {
+		labels = append(labels, "server")
+	}
+	infos := []*metric_info.MetricInfo{
+		{Type: metric_info.Counter, Name: MetricSLOEvents, Help: "参与 SLO 统计的请求数", Labels: slices.Clone(labels)},
+		{Type: metric_info.Counter, Name: MetricSLOGoodEvents, Help: "满足 SLO 的请求数", Labels: slices.Clone(labels)},
+		{Type: metric_info.Gauge, Name: MetricSLOObjective, Help: "SLO 目标值", Labels: slices.Clone(labels)},
+	}
+	if len(b.slo.windows) > 0 {
+		infos = append(infos, &metric_info.MetricInfo{
+			Type: metric_info.Gauge, Name: MetricSLOBurnRate, Help: "SLO 燃烧率，1 表示恰好在窗口内耗尽错误预算",
+			Labels: append(slices.Clone(labels), "window"),
+		})
+	}
+	return infos
+}
+
+// validateSLO 校验目标与燃烧率窗口，在注册任何指标之前调用，避免配置错误时留下部分注册的指标
+func (b *BaseMetricsMiddleware) validateSLO() error {
+	if len(b.slo.objectives) == 0 {
+		return nil
+	}
+	for _, window := range b.slo.windows {
+		if window <= 0 {
+			return fmt.Errorf("slo burn rate window %v must be positive", window)
+		}
+	}
+	for _, obj := range b.slo.objectives {
+		if (obj.Latency > 0) != (obj.LatencyTarget > 0) {
+			return fmt.Errorf("slo of route %q: Latency and LatencyTarget must be set together", obj.Route)
+		}
+		if obj.LatencyTarget == 0 && obj.Availability == 0 {
+			return fmt.Errorf("slo of route %q has no objective", obj.Route)
+		}
+		for _, target := range []float64{obj.LatencyTarget, obj.Availability} {
+			if target < 0 || target >= 1 {
+				return fmt.Errorf("slo target %v of route %q must be in (0, 1)", target, obj.Route)
+			}
+		}
+	}
+	return nil
+}
+
+// initSLO 创建统计状态并上报目标值，在 validateSLO 通过且注册指标之后调用
+func (b *BaseMetricsMiddleware) initSLO(ctx context.Context) {
+	if len(b.slo.objectives) == 0 {
+		return
+	}
+
+	var resolution time.Duration
+	if len(b.slo.windows) > 0 {
+		// 精度为最短窗口的十分之一，环形缓冲区覆盖最长窗口
+		resolution = max(b.slo.windows[0]/10, time.Second)
+	}
+	trackers := make(map[string][]*sloTracker, len(b.slo.objectives))
+	for _, obj := range b.slo.objectives {
+		for _, t := range []*sloTracker{
+			{objective: sloLatency, target: obj.LatencyTarget, latency: obj.Latency},
+			{objective: sloAvailability, target: obj.Availability},
+		} {
+			if t.target == 0 {
+				continue
+			}
+			t.labels = map[string]string{"route": obj.Route, "objective": t.objective}
+			if b.server != "" {
+				t.labels["server"] = b.server
+			}
+			if resolution > 0 {
+				t.events = newEventWindow(resolution, b.slo.windows[len(b.slo.windows)-1])
+			}
+			b.report(ctx, MetricSLOObjective, t.labels, t.target)
+			trackers[obj.Route] = append(trackers[obj.Route], t)
+		}
+	}
+	b.slo.trackers = trackers
+
+	if resolution > 0 && b.enabled(MetricSLOBurnRate) {
+		b.slo.done = make(chan struct{})
+		go b.updateBurnRates(resolution)
+	}
+}
+
+// recordSLO 记录一次请求对所属路由各个目标的影响
+func (b *BaseMetricsMiddleware) recordSLO(o *Observation, status int, elapsed time.Duration) {
+	trackers := b.slo.trackers[o.route]
+	if len(trackers) == 0 {
+		return
+	}
+	isError := b.statusLabels.isErr(o.request, status)
+	now := time.Now()
+	for _, t := range trackers {
+		good := t.good(isError, elapsed)
+		b.report(o.ctx, MetricSLOEvents, t.labels, 1)
+		if good {
+			b.report(o.ctx, MetricSLOGoodEvents, t.labels, 1)
+		}
+		if t.events != nil {
+			t.events.record(now, good)
+		}
+	}
+}
+
+// updateBurnRates 每隔 resolution 计算一次所有目标在各个窗口内的燃烧率，直到 Close
+func (b *BaseMetricsMiddleware) updateBurnRates(resolution time.Duration) {
+	ticker := time.NewTicker(resolution)
+	defer ticker.Stop()
+
+	windows := make([]string, len(b.slo.windows))
+	for i, window := range b.slo.windows {
+		windows[i] = formatWindow(window)
+	}
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, trackers := range b.slo.trackers {
+				for _, t := range trackers {
+					for i, window := range b.slo.windows {
+						bad, total := t.events.count(now, window)
+						b.report(context.TODO(), MetricSLOBurnRate, mergeLabels(t.labels, map[string]string{"window": windows[i]}), burnRate(bad, total, t.target))
+					}
+				}
+			}
+		case <-b.slo.done:
+			return
+		}
+	}
+}
+
+// burnRate 返回错误率与错误预算（1 - target）之比，窗口内没有请求时为 0
+func burnRate(bad, total int64, target float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total) / (1 - target)
+}
+
+// formatWindow 将窗口格式化为 PromQL 风格的 5m、1h
+func formatWindow(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	default:
+		return d.String()
+	}
+}
+
+// eventBucket 是一个时间片内的请求数
+type eventBucket struct {
+	bad, total int64
+}
+
+// eventWindow 以 resolution 为时间片、用环形缓冲区记录最近一段时间内的请求数
+type eventWindow struct {
+	mu         sync.Mutex
+	resolution time.Duration
+	buckets    []eventBucket
+	head       int   // 当前时间片在 buckets 中的位置
+	headSlot   int64 // 当前时间片的序号，即 UnixNano / resolution
+}
+
+func newEventWindow(resolution, span time.Duration) *eventWindow {
+	n := int((span + resolution - 1) / resolution)
+	return &eventWindow{
+		resolution: resolution,
+		buckets:    make([]eventBucket, n),
+	}
+}
+
+func (w *eventWindow) record(now time.Time, good bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.advance(now)
+	w.buckets[w.head].total++
+	if !good {
+		w.buckets[w.head].bad++
+	}
+}
+
+// count 返回最近 window 内不满足目标的请求数与总请求数
+func (w *eventWindow) count(now time.Time, window time.Duration) (bad, total int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.advance(now)
+	n := min(int((window+w.resolution-1)/w.resolution), len(w.buckets))
+	for i := 0; i < n; i++ {
+		b := w.buckets[(w.head-i+len(w.buckets))%len(w.buckets)]
+		bad += b.bad
+		total += b.total
+	}
+	return bad, total
+}
+
+// advance 将当前时间片移动到 now 所在的时间片，并清空经过的时间片，调用方需持有 w.mu
+func (w *eventWindow) advance(now time.Time) {
+	slot := now.UnixNano() / int64(w.resolution)
+	steps := slot - w.headSlot
+	if steps <= 0 {
+		return
+	}
+	if steps >= int64(len(w.buckets)) {
+		clear(w.buckets)
+	} else {
+		for i := int64(0); i < steps; i++ {
+			w.head = (w.head + 1) % len(w.buckets)
+			w.buckets[w.head] = eventBucket{}
+		}
+	}
+	w.headSlot = slot
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestEventWindow 覆盖同一时间片内的累加、按窗口截取、环形缓冲区回绕以及长时间无请求后的清空
+func TestEventWindow(t *testing.T) {
+	type event struct {
+		at   time.Duration
+		good bool
+	}
+	// 精度 1s、覆盖 5s，即 5 个时间片
+	const resolution, span = time.Second, 5 * time.Second
+
+	cases := []struct {
+		name       string
+		events     []event
+		at, window time.Duration
+		bad, total int64
+	}{
+		{"Empty", nil, 0, span, 0, 0},
+		{"SameSlot", []event{{0, true}, {500 * time.Millisecond, false}}, 900 * time.Millisecond, time.Second, 1, 2},
+		{"ShortWindow", []event{{0, false}, {time.Second, true}, {2 * time.Second, true}}, 2 * time.Second, 2 * time.Second, 0, 2},
+		{"LongWindow", []event{{0, false}, {time.Second, true}, {2 * time.Second, true}}, 2 * time.Second, 3 * time.Second, 1, 3},
+		{"WindowRoundsUp", []event{{0, false}, {time.Second, true}}, time.Second, 1500 * time.Millisecond, 1, 2},
+		{"WindowClampedToSpan", []event{{0, false}, {4 * time.Second, true}}, 4 * time.Second, time.Hour, 1, 2},
+		{"Expire", []event{{0, false}}, 5 * time.Second, span, 0, 0},
+		{"NotYetExpired", []event{{0, false}}, 4 * time.Second, span, 1, 1},
+		{"WrapAround", []event{{0, false}, {3 * time.Second, true}, {5 * time.Second, true}, {7 * time.Second, false}}, 7 * time.Second, span, 1, 3},
+		{"WrapAroundTwice", []event{{0, false}, {4 * time.Second, false}, {9 * time.Second, true}, {12 * time.Second, true}}, 12 * time.Second, span, 0, 2},
+		{"LongGap", []event{{0, false}, {time.Second, false}, {2 * time.Second, false}, {3 * time.Second, false}, {4 * time.Second, false}}, 100 * time.Second, span, 0, 0},
+		{"RecordAfterLongGap", []event{{0, false}, {2 * time.Second, false}, {4 * time.Second, false}, {100 * time.Second, true}}, 100 * time.Second, span, 0, 1},
+	}
+
+	base := time.Unix(1_700_000_000, 0)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := newEventWindow(resolution, span)
+			for _, e := range c.events {
+				w.record(base.Add(e.at), e.good)
+			}
+			bad, total := w.count(base.Add(c.at), c.window)
+			if bad != c.bad || total != c.total {
+				t.Errorf("count(%v, %v) = (%d, %d), want (%d, %d)", c.at, c.window, bad, total, c.bad, c.total)
+			}
+		})
+	}
+}
+
+func TestBurnRate(t *testing.T) {
+	cases := []struct {
+		name       string
+		bad, total int64
+		target     float64
+		want       float64
+	}{
+		{"NoRequests", 0, 0, 0.99, 0},
+		{"NoErrors", 0, 100, 0.999, 0},
+		{"ExactlyBudget", 1, 100, 0.99, 1},
+		{"FastBurn", 5, 100, 0.99, 5},
+		{"AllBad", 10, 10, 0.9, 10},
+		{"UnderBudget", 1, 1000, 0.99, 0.1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := burnRate(c.bad, c.total, c.target); math.Abs(got-c.want) > 1e-9 {
+				t.Errorf("burnRate(%d, %d, %v) = %v, want %v", c.bad, c.total, c.target, got, c.want)
+			}
+		})
+	}
+}
+
+// TestValidateSLO 校验发生在注册之前，因此只需要配置而不需要初始化 metrics
+func TestValidateSLO(t *testing.T) {
+	cases := []struct {
+		name       string
+		objectives []Objective
+		windows    []time.Duration
+		wantErr    bool
+	}{
+		{"None", nil, []time.Duration{0}, false},
+		{"Latency", []Objective{{Route: "/a", Latency: 100 * time.Millisecond, LatencyTarget: 0.99}}, nil, false},
+		{"Both", []Objective{{Route: "/a", Latency: time.Second, LatencyTarget: 0.9, Availability: 0.999}}, defaultBurnRateWindows, false},
+		{"NonPositiveWindow", []Objective{{Route: "/a", Availability: 0.99}}, []time.Duration{time.Hour, 0}, true},
+		{"LatencyWithoutTarget", []Objective{{Route: "/a", Latency: time.Second}}, nil, true},
+		{"TargetWithoutLatency", []Objective{{Route: "/a", LatencyTarget: 0.99}}, nil, true},
+		{"NoObjective", []Objective{{Route: "/a"}}, nil, true},
+		{"TargetTooHigh", []Objective{{Route: "/a", Availability: 1}}, nil, true},
+		{"NegativeTarget", []Objective{{Route: "/a", Availability: -0.5}}, nil, true},
+		{"SecondInvalid", []Objective{{Route: "/a", Availability: 0.99}, {Route: "/b"}}, nil, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := &BaseMetricsMiddleware{}
+			b.WithSLO(c.objectives...)
+			if c.windows != nil {
+				b.WithSLOBurnRate(c.windows...)
+			}
+			if err := b.validateSLO(); (err != nil) != c.wantErr {
+				t.Errorf("validateSLO() = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
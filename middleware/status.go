@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// statusLabels 决定 status 与 error 标签的取值
+type statusLabels struct {
+	class   bool                                   // 是否按类别（2xx、4xx、5xx）记录状态码
+	isError func(r *http.Request, status int) bool // 为 nil 时使用 defaultIsError
+}
+
+// defaultIsError 将 5xx 视为错误
+func defaultIsError(_ *http.Request, status int) bool {
+	return status >= http.StatusInternalServerError
+}
+
+// status 返回状态码对应的 status 标签值
+func (s *statusLabels) status(status int) string {
+	if s.class {
+		return strconv.Itoa(status/100) + "xx"
+	}
+	return strconv.Itoa(status)
+}
+
+// error 返回 error 标签值（"true" 或 "false"）
+func (s *statusLabels) error(r *http.Request, status int) string {
+	return strconv.FormatBool(s.isErr(r, status))
+}
+
+// isErr 判断一次请求是否为错误
+func (s *statusLabels) isErr(r *http.Request, status int) bool {
+	if s.isError == nil {
+		return defaultIsError(r, status)
+	}
+	return s.isError(r, status)
+}
+
+// WithStatusClass 按类别（2xx、3xx、4xx、5xx）而不是具体状态码记录所有内置指标的 status 标签，用于降低基数
+func (b *BaseMetricsMiddleware) WithStatusClass(enabled bool) {
+	b.statusLabels.class = enabled
+}
+
+// WithErrorClassifier 设置 status_code 指标中 error 标签的判断函数，默认将 5xx 视为错误
+func (b *BaseMetricsMiddleware) WithErrorClassifier(isError func(r *http.Request, status int) bool) {
+	b.statusLabels.isError = isError
+}
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// firstByte 记录从请求开始到第一次写出响应（状态码或响应体）的时间
+type firstByte struct {
+	start   time.Time
+	elapsed time.Duration // 尚未写出时为 0
+}
+
+func (f *firstByte) mark() {
+	if f.elapsed == 0 {
+		f.elapsed = max(time.Since(f.start), time.Nanosecond)
+	}
+}
+
+// responseWriter 是一个包装了 http.ResponseWriter 的结构体，用于捕获状态码、响应大小以及首字节时间，
+// 通过 newResponseWriter 创建，以便只暴露底层 ResponseWriter 支持的可选接口
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	size       int
+	firstByte  firstByte
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	rw.firstByte.mark()
+	rw.statusCode = statusCode
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.firstByte.mark()
+	size, err := rw.ResponseWriter.Write(b)
+	rw.size += size
+	return size, err
+}
+
+// Unwrap 返回底层的 ResponseWriter，供 http.ResponseController 使用
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// 以下类型为 responseWriter 实现对应的可选接口，仅在底层 ResponseWriter 支持时才会被组合进来
+type (
+	flusher    struct{ *responseWriter }
+	hijacker   struct{ *responseWriter }
+	pusher     struct{ *responseWriter }
+	readerFrom struct{ *responseWriter }
+)
+
+func (f flusher) Flush() {
+	f.firstByte.mark()
+	f.ResponseWriter.(http.Flusher).Flush()
+}
+
+// Hijack 成功后连接交给处理器（如 WebSocket），之后的写出不再经过 ResponseWriter，
+// 因此按照协议升级记录状态码与首字节时间
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := h.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		h.firstByte.mark()
+		h.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, buf, err
+}
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	return p.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	r.firstByte.mark()
+	n, err := r.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	r.size += int(n)
+	return n, err
+}
+
+const (
+	supportsFlusher = 1 << iota
+	supportsHijacker
+	supportsPusher
+	supportsReaderFrom
+)
+
+// newResponseWriter 包装 w，返回用于记录的 responseWriter 以及传给下一个处理器的 http.ResponseWriter，
+// 后者恰好实现了 w 所支持的 http.Flusher、http.Hijacker、http.Pusher 与 io.ReaderFrom
+func newResponseWriter(w http.ResponseWriter, start time.Time) (*responseWriter, http.ResponseWriter) {
+	rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK, firstByte: firstByte{start: start}}
+
+	supports := 0
+	if _, ok := w.(http.Flusher); ok {
+		supports |= supportsFlusher
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		supports |= supportsHijacker
+	}
+	if _, ok := w.(http.Pusher); ok {
+		supports |= supportsPusher
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		supports |= supportsReaderFrom
+	}
+
+	switch supports {
+	case 0:
+		return rw, rw
+	case supportsFlusher:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+		}{rw, flusher{rw}}
+	case supportsHijacker:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+		}{rw, hijacker{rw}}
+	case supportsFlusher | supportsHijacker:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+		}{rw, flusher{rw}, hijacker{rw}}
+	case supportsPusher:
+		return rw, struct {
+			*responseWriter
+			http.Pusher
+		}{rw, pusher{rw}}
+	case supportsFlusher | supportsPusher:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Pusher
+		}{rw, flusher{rw}, pusher{rw}}
+	case supportsHijacker | supportsPusher:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+		}{rw, hijacker{rw}, pusher{rw}}
+	case supportsFlusher | supportsHijacker | supportsPusher:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{rw, flusher{rw}, hijacker{rw}, pusher{rw}}
+	case supportsReaderFrom:
+		return rw, struct {
+			*responseWriter
+			io.ReaderFrom
+		}{rw, readerFrom{rw}}
+	case supportsFlusher | supportsReaderFrom:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			io.ReaderFrom
+		}{rw, flusher{rw}, readerFrom{rw}}
+	case supportsHijacker | supportsReaderFrom:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, hijacker{rw}, readerFrom{rw}}
+	case supportsFlusher | supportsHijacker | supportsReaderFrom:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, flusher{rw}, hijacker{rw}, readerFrom{rw}}
+	case supportsPusher | supportsReaderFrom:
+		return rw, struct {
+			*responseWriter
+			http.Pusher
+			io.ReaderFrom
+		}{rw, pusher{rw}, readerFrom{rw}}
+	case supportsFlusher | supportsPusher | supportsReaderFrom:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+		}{rw, flusher{rw}, pusher{rw}, readerFrom{rw}}
+	case supportsHijacker | supportsPusher | supportsReaderFrom:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{rw, hijacker{rw}, pusher{rw}, readerFrom{rw}}
+	default:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{rw, flusher{rw}, hijacker{rw}, pusher{rw}, readerFrom{rw}}
+	}
+}
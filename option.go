@@ -9,6 +9,20 @@ import (
 // Option 定义了一个函数类型，用于设置配置选项
 type Option func(*config.MetricsConfig)
 
+// WithConfigFile 从 YAML 或 JSON 文件加载配置，文件中的配置会被环境变量与其他 Option 覆盖
+func WithConfigFile(path string) Option {
+	return func(c *config.MetricsConfig) {
+		c.ConfigFile = path
+	}
+}
+
+// WithCatalog 设置指标目录文件，其中定义的指标会在 Init 时自动注册
+func WithCatalog(paths ...string) Option {
+	return func(c *config.MetricsConfig) {
+		c.CatalogFiles = append([]string(nil), paths...)
+	}
+}
+
 // WithCollectorMode 设置为 Collector 模式
 func WithCollectorMode(port int) Option {
 	return func(c *config.MetricsConfig) {
@@ -27,6 +41,16 @@ func WithPushgatewayMode(pushAddr, jobName string, pushInterval time.Duration) O
 	}
 }
 
+// WithFileMode 设置为 File 模式，定期将指标写入文件（path 为空或 "-" 时写入标准输出）
+func WithFileMode(path string, format config.FileFormat, flushInterval time.Duration) Option {
+	return func(c *config.MetricsConfig) {
+		c.ReportType = config.FileType
+		c.FilePath = path
+		c.FileFormat = format
+		c.FlushInterval = flushInterval
+	}
+}
+
 // WithNamespace 设置 namespace
 func WithNamespace(namespace string) Option {
 	return func(c *config.MetricsConfig) {
@@ -40,3 +64,107 @@ func WithSubsystem(subsystem string) Option {
 		c.Subsystem = subsystem
 	}
 }
+
+// WithConstLabels 设置附加到所有指标上的常量标签，如 env、region、cluster、version，可多次调用进行合并
+func WithConstLabels(labels map[string]string) Option {
+	return func(c *config.MetricsConfig) {
+		if c.ConstLabels == nil {
+			c.ConstLabels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			c.ConstLabels[k] = v
+		}
+	}
+}
+
+// WithSeriesLimit 设置时间序列数量上限，total 为全部指标的总上限，perMetric 为单个指标的默认上限，0 表示不限制
+func WithSeriesLimit(total, perMetric int) Option {
+	return func(c *config.MetricsConfig) {
+		c.MaxSeries = total
+		c.MaxSeriesPerMetric = perMetric
+	}
+}
+
+// WithOverflowValue 设置超出基数限制或不在白名单内时使用的标签值
+func WithOverflowValue(value string) Option {
+	return func(c *config.MetricsConfig) {
+		c.OverflowValue = value
+	}
+}
+
+// WithLabelMode 设置上报标签校验的严格程度
+func WithLabelMode(mode config.LabelMode) Option {
+	return func(c *config.MetricsConfig) {
+		c.LabelMode = mode
+	}
+}
+
+// WithSweepInterval 设置检查过期标签组合（MetricInfo.TTL）的间隔
+func WithSweepInterval(interval time.Duration) Option {
+	return func(c *config.MetricsConfig) {
+		c.SweepInterval = interval
+	}
+}
+
+// WithGoCollector 开启或关闭 Go 运行时指标，runtimeMetrics 为额外采集的 runtime/metrics 指标的正则表达式
+func WithGoCollector(enabled bool, runtimeMetrics ...string) Option {
+	return func(c *config.MetricsConfig) {
+		c.GoCollector = enabled
+		c.GoRuntimeMetrics = runtimeMetrics
+	}
+}
+
+// WithProcessCollector 开启或关闭进程指标
+func WithProcessCollector(enabled bool) Option {
+	return func(c *config.MetricsConfig) {
+		c.ProcessCollector = enabled
+	}
+}
+
+// WithBuildInfoCollector 开启或关闭构建信息指标
+func WithBuildInfoCollector(enabled bool) Option {
+	return func(c *config.MetricsConfig) {
+		c.BuildInfoCollector = enabled
+	}
+}
+
+// WithVersion 设置 build_info 指标中的版本与提交信息，优先级高于通过 ldflags 注入的 Version 与 Commit
+func WithVersion(version, commit string) Option {
+	return func(c *config.MetricsConfig) {
+		c.Version = version
+		c.Commit = commit
+	}
+}
+
+// WithNameValidation 设置指标名与标签名不符合 Prometheus 命名规范时的处理方式
+func WithNameValidation(mode config.NameValidation) Option {
+	return func(c *config.MetricsConfig) {
+		c.NameValidation = mode
+	}
+}
+
+// WithSummaryDefaults 设置 Summary 的默认统计窗口、窗口桶数与样本缓冲区大小，0 表示使用 Prometheus 的默认值
+func WithSummaryDefaults(maxAge time.Duration, ageBuckets, bufCap uint32) Option {
+	return func(c *config.MetricsConfig) {
+		c.SummaryMaxAge = maxAge
+		c.SummaryAgeBuckets = ageBuckets
+		c.SummaryBufCap = bufCap
+	}
+}
+
+// WithAsync 开启异步上报，上报先进入大小为 queueSize 的队列，由 workers 个后台协程写入，队列已满时按照 policy 处理
+func WithAsync(queueSize, workers int, policy config.DropPolicy) Option {
+	return func(c *config.MetricsConfig) {
+		c.Async = true
+		c.AsyncQueueSize = queueSize
+		c.AsyncWorkers = workers
+		c.AsyncDropPolicy = policy
+	}
+}
+
+// WithDebug 为每次上报输出 Debug 日志，默认关闭以避免热路径上的额外开销
+func WithDebug(enabled bool) Option {
+	return func(c *config.MetricsConfig) {
+		c.Debug = enabled
+	}
+}
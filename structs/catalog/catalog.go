@@ -0,0 +1,258 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/everfir/metrics-go/structs/metric_info"
+	"gopkg.in/yaml.v3"
+)
+
+// Catalog 是从文件中加载的一组指标定义
+type Catalog struct {
+	metrics []metric_info.MetricInfo
+	index   map[metric_info.MetricName]int
+}
+
+// entry 是目录文件中一个指标的定义
+type entry struct {
+	Name           string              `yaml:"name"`
+	Type           string              `yaml:"type"`
+	Help           string              `yaml:"help"`
+	Unit           string              `yaml:"unit"`
+	Labels         []string            `yaml:"labels"`
+	LabelDefaults  map[string]string   `yaml:"label_defaults"`
+	LabelAllowlist map[string][]string `yaml:"label_allowlist"`
+	ConstLabels    map[string]string   `yaml:"const_labels"`
+	Buckets        []float64           `yaml:"buckets"`
+	Objectives     map[float64]float64 `yaml:"objectives"`
+	MaxAge         string              `yaml:"max_age"`
+	AgeBuckets     uint32              `yaml:"age_buckets"`
+	BufCap         uint32              `yaml:"buf_cap"`
+	MaxSeries      int                 `yaml:"max_series"`
+	TTL            string              `yaml:"ttl"`
+}
+
+// entryKeys 是 entry 支持的所有字段名
+var entryKeys = func() map[string]struct{} {
+	keys := make(map[string]struct{})
+	t := reflect.TypeOf(entry{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		keys[name] = struct{}{}
+	}
+	return keys
+}()
+
+// document 是目录文件的顶层结构
+type document struct {
+	Metrics yaml.Node `yaml:"metrics"`
+}
+
+// ValidationError 描述目录文件中某个位置的错误
+type ValidationError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Load 从 YAML 文件中加载指标目录
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read catalog: %w", err)
+	}
+	return Parse(path, data)
+}
+
+// Parse 解析 YAML 格式的指标目录，file 仅用于错误信息，返回的错误包含所有校验失败的位置
+func Parse(file string, data []byte) (*Catalog, error) {
+	var doc document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if doc.Metrics.Kind == 0 {
+		return &Catalog{index: map[metric_info.MetricName]int{}}, nil
+	}
+	if doc.Metrics.Kind != yaml.SequenceNode {
+		return nil, &ValidationError{File: file, Line: doc.Metrics.Line, Msg: "metrics must be a list"}
+	}
+
+	c := &Catalog{index: make(map[metric_info.MetricName]int, len(doc.Metrics.Content))}
+	lines := make(map[metric_info.MetricName]int, len(doc.Metrics.Content))
+	var errs []error
+	fail := func(line int, format string, args ...any) {
+		errs = append(errs, &ValidationError{File: file, Line: line, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	for _, node := range doc.Metrics.Content {
+		// 与配置文件一致，不允许未知字段，避免 lables 之类的拼写错误被静默忽略
+		if unknown := unknownKeys(node); len(unknown) > 0 {
+			for _, key := range unknown {
+				fail(key.Line, "unknown field %q", key.Value)
+			}
+			continue
+		}
+		var e entry
+		if err := node.Decode(&e); err != nil {
+			fail(node.Line, "%v", err)
+			continue
+		}
+
+		info, problems := e.toMetricInfo()
+		for _, p := range problems {
+			fail(node.Line, "metric %q: %s", e.Name, p)
+		}
+		if len(problems) > 0 {
+			continue
+		}
+		if line, exists := lines[info.Name]; exists {
+			fail(node.Line, "metric %q already defined at line %d", e.Name, line)
+			continue
+		}
+
+		lines[info.Name] = node.Line
+		c.index[info.Name] = len(c.metrics)
+		c.metrics = append(c.metrics, info)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return c, nil
+}
+
+// unknownKeys 返回一个指标定义中 entry 不支持的字段
+func unknownKeys(node *yaml.Node) (unknown []*yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if _, ok := entryKeys[key.Value]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	return unknown
+}
+
+// toMetricInfo 校验并转换为 MetricInfo，返回所有发现的问题
+func (e *entry) toMetricInfo() (info metric_info.MetricInfo, problems []string) {
+	if e.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	if e.Help == "" {
+		problems = append(problems, "help is required")
+	}
+	typ, err := metric_info.ParseMetricType(e.Type)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	seen := make(map[string]struct{}, len(e.Labels))
+	for _, label := range e.Labels {
+		if _, ok := seen[label]; ok {
+			problems = append(problems, fmt.Sprintf("duplicate label %q", label))
+		}
+		seen[label] = struct{}{}
+	}
+	for _, field := range []struct {
+		name   string
+		labels []string
+	}{
+		{"label_defaults", keys(e.LabelDefaults)},
+		{"label_allowlist", keys(e.LabelAllowlist)},
+	} {
+		for _, label := range field.labels {
+			if _, ok := seen[label]; !ok {
+				problems = append(problems, fmt.Sprintf("%s refers to undeclared label %q", field.name, label))
+			}
+		}
+	}
+	for label := range e.ConstLabels {
+		if _, ok := seen[label]; ok {
+			problems = append(problems, fmt.Sprintf("const label %q is also a variable label", label))
+		}
+	}
+
+	if err == nil && len(e.Buckets) > 0 && typ != metric_info.Histogram {
+		problems = append(problems, "buckets are only valid for histograms")
+	}
+	if !sort.Float64sAreSorted(e.Buckets) {
+		problems = append(problems, "buckets must be in increasing order")
+	}
+	if err == nil && typ != metric_info.Summary && (len(e.Objectives) > 0 || e.MaxAge != "" || e.AgeBuckets > 0 || e.BufCap > 0) {
+		problems = append(problems, "objectives, max_age, age_buckets and buf_cap are only valid for summaries")
+	}
+	if e.MaxSeries < 0 {
+		problems = append(problems, "max_series cannot be negative")
+	}
+
+	var ttl time.Duration
+	if e.TTL != "" {
+		if ttl, err = time.ParseDuration(e.TTL); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid ttl: %v", err))
+		}
+	}
+
+	var maxAge time.Duration
+	if e.MaxAge != "" {
+		if maxAge, err = time.ParseDuration(e.MaxAge); err != nil || maxAge < 0 {
+			problems = append(problems, fmt.Sprintf("invalid max_age %q", e.MaxAge))
+		}
+	}
+
+	info = metric_info.MetricInfo{
+		Type:           typ,
+		Name:           metric_info.NewMetricName(e.Name),
+		Help:           e.Help,
+		Buckets:        e.Buckets,
+		Objectives:     e.Objectives,
+		MaxAge:         maxAge,
+		AgeBuckets:     e.AgeBuckets,
+		BufCap:         e.BufCap,
+		Unit:           e.Unit,
+		ConstLabels:    e.ConstLabels,
+		Labels:         e.Labels,
+		LabelDefaults:  e.LabelDefaults,
+		MaxSeries:      e.MaxSeries,
+		LabelAllowlist: e.LabelAllowlist,
+		TTL:            ttl,
+	}
+	if err := info.ValidateObjectives(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return info, problems
+}
+
+// Lookup 根据名称查找指标定义
+func (c *Catalog) Lookup(name metric_info.MetricName) (metric_info.MetricInfo, bool) {
+	i, ok := c.index[name]
+	if !ok {
+		return metric_info.MetricInfo{}, false
+	}
+	return c.metrics[i], true
+}
+
+// Metrics 按照文件中的顺序返回所有指标定义
+func (c *Catalog) Metrics() []metric_info.MetricInfo {
+	return append([]metric_info.MetricInfo(nil), c.metrics...)
+}
+
+func keys[V any](m map[string]V) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 支持的环境变量
+const (
+	EnvNamespace          = "Namespace"
+	EnvSystem             = "System"
+	EnvConfigFile         = "METRICS_CONFIG_FILE"
+	EnvReportType         = "METRICS_REPORT_TYPE" // collector / pushgateway / file
+	EnvPort               = "METRICS_PORT"
+	EnvPushAddr           = "METRICS_PUSH_ADDR"
+	EnvJobName            = "METRICS_JOB_NAME"
+	EnvPushInterval       = "METRICS_PUSH_INTERVAL" // 如 10s
+	EnvFilePath           = "METRICS_FILE_PATH"
+	EnvFileFormat         = "METRICS_FILE_FORMAT" // text / openmetrics / jsonl
+	EnvFlushInterval      = "METRICS_FLUSH_INTERVAL"
+	EnvConstLabels        = "METRICS_CONST_LABELS" // 如 env=prod,region=cn-east
+	EnvMaxSeries          = "METRICS_MAX_SERIES"
+	EnvMaxSeriesPerMetric = "METRICS_MAX_SERIES_PER_METRIC"
+	EnvLabelMode          = "METRICS_LABEL_MODE"      // lenient / strict
+	EnvCatalogFiles       = "METRICS_CATALOG_FILES"   // 逗号分隔的多个文件
+	EnvNameValidation     = "METRICS_NAME_VALIDATION" // warn / reject / fix
+)
+
+// fileConfig 是配置文件的结构，未出现的字段不会覆盖已有配置
+type fileConfig struct {
+	CatalogFiles []string `yaml:"catalog_files" json:"catalog_files"`
+
+	ReportType   *ReportType `yaml:"report_type" json:"report_type"`
+	Namespace    *string     `yaml:"namespace" json:"namespace"`
+	Subsystem    *string     `yaml:"subsystem" json:"subsystem"`
+	Port         *int        `yaml:"port" json:"port"`
+	PushAddr     *string     `yaml:"push_addr" json:"push_addr"`
+	JobName      *string     `yaml:"job_name" json:"job_name"`
+	PushInterval *string     `yaml:"push_interval" json:"push_interval"`
+
+	FilePath      *string     `yaml:"file_path" json:"file_path"`
+	FileFormat    *FileFormat `yaml:"file_format" json:"file_format"`
+	FlushInterval *string     `yaml:"flush_interval" json:"flush_interval"`
+
+	ConstLabels map[string]string `yaml:"const_labels" json:"const_labels"`
+
+	MaxSeries          *int    `yaml:"max_series" json:"max_series"`
+	MaxSeriesPerMetric *int    `yaml:"max_series_per_metric" json:"max_series_per_metric"`
+	OverflowValue      *string `yaml:"overflow_value" json:"overflow_value"`
+
+	LabelMode      *LabelMode      `yaml:"label_mode" json:"label_mode"`
+	NameValidation *NameValidation `yaml:"name_validation" json:"name_validation"`
+	SweepInterval  *string         `yaml:"sweep_interval" json:"sweep_interval"`
+	Debug          *bool           `yaml:"debug" json:"debug"`
+
+	Async           *bool       `yaml:"async" json:"async"`
+	AsyncQueueSize  *int        `yaml:"async_queue_size" json:"async_queue_size"`
+	AsyncWorkers    *int        `yaml:"async_workers" json:"async_workers"`
+	AsyncDropPolicy *DropPolicy `yaml:"async_drop_policy" json:"async_drop_policy"`
+
+	SummaryMaxAge     *string `yaml:"summary_max_age" json:"summary_max_age"`
+	SummaryAgeBuckets *uint32 `yaml:"summary_age_buckets" json:"summary_age_buckets"`
+	SummaryBufCap     *uint32 `yaml:"summary_buf_cap" json:"summary_buf_cap"`
+
+	GoCollector        *bool    `yaml:"go_collector" json:"go_collector"`
+	GoRuntimeMetrics   []string `yaml:"go_runtime_metrics" json:"go_runtime_metrics"`
+	ProcessCollector   *bool    `yaml:"process_collector" json:"process_collector"`
+	BuildInfoCollector *bool    `yaml:"build_info_collector" json:"build_info_collector"`
+	Version            *string  `yaml:"version" json:"version"`
+	Commit             *string  `yaml:"commit" json:"commit"`
+}
+
+// LoadFile 从 YAML（.yaml/.yml）或 JSON（.json）文件中加载配置并覆盖 c 中对应的字段
+func (c *MetricsConfig) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	var fc fileConfig
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&fc)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&fc)
+	default:
+		return fmt.Errorf("unsupported config file extension %q", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	if err := fc.apply(c); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (fc *fileConfig) apply(c *MetricsConfig) error {
+	setValue(&c.ReportType, fc.ReportType)
+	setValue(&c.Namespace, fc.Namespace)
+	setValue(&c.Subsystem, fc.Subsystem)
+	setValue(&c.Port, fc.Port)
+	setValue(&c.PushAddr, fc.PushAddr)
+	setValue(&c.JobName, fc.JobName)
+	setValue(&c.FilePath, fc.FilePath)
+	setValue(&c.FileFormat, fc.FileFormat)
+	setValue(&c.MaxSeries, fc.MaxSeries)
+	setValue(&c.MaxSeriesPerMetric, fc.MaxSeriesPerMetric)
+	setValue(&c.OverflowValue, fc.OverflowValue)
+	setValue(&c.LabelMode, fc.LabelMode)
+	setValue(&c.NameValidation, fc.NameValidation)
+	setValue(&c.Debug, fc.Debug)
+	setValue(&c.Async, fc.Async)
+	setValue(&c.AsyncQueueSize, fc.AsyncQueueSize)
+	setValue(&c.AsyncWorkers, fc.AsyncWorkers)
+	setValue(&c.AsyncDropPolicy, fc.AsyncDropPolicy)
+	setValue(&c.SummaryAgeBuckets, fc.SummaryAgeBuckets)
+	setValue(&c.SummaryBufCap, fc.SummaryBufCap)
+	setValue(&c.GoCollector, fc.GoCollector)
+	setValue(&c.ProcessCollector, fc.ProcessCollector)
+	setValue(&c.BuildInfoCollector, fc.BuildInfoCollector)
+	setValue(&c.Version, fc.Version)
+	setValue(&c.Commit, fc.Commit)
+	if fc.CatalogFiles != nil {
+		c.CatalogFiles = fc.CatalogFiles
+	}
+	if fc.GoRuntimeMetrics != nil {
+		c.GoRuntimeMetrics = fc.GoRuntimeMetrics
+	}
+	c.mergeConstLabels(fc.ConstLabels)
+
+	for _, d := range []struct {
+		name  string
+		value *string
+		dst   *time.Duration
+	}{
+		{"push_interval", fc.PushInterval, &c.PushInterval},
+		{"flush_interval", fc.FlushInterval, &c.FlushInterval},
+		{"sweep_interval", fc.SweepInterval, &c.SweepInterval},
+		{"summary_max_age", fc.SummaryMaxAge, &c.SummaryMaxAge},
+	} {
+		if d.value == nil {
+			continue
+		}
+		v, err := time.ParseDuration(*d.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", d.name, err)
+		}
+		*d.dst = v
+	}
+	return nil
+}
+
+// LoadEnv 从环境变量中加载配置并覆盖 c 中对应的字段，未设置或为空的环境变量会被忽略
+func (c *MetricsConfig) LoadEnv() error {
+	var errs []string
+	lookup := func(key string, parse func(string) error) {
+		value, ok := os.LookupEnv(key)
+		if !ok || value == "" {
+			return
+		}
+		if err := parse(value); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	str := func(dst *string) func(string) error {
+		return func(v string) error { *dst = v; return nil }
+	}
+	num := func(dst *int) func(string) error {
+		return func(v string) (err error) { *dst, err = strconv.Atoi(v); return }
+	}
+	dur := func(dst *time.Duration) func(string) error {
+		return func(v string) (err error) { *dst, err = time.ParseDuration(v); return }
+	}
+
+	lookup(EnvNamespace, str(&c.Namespace))
+	lookup(EnvSystem, str(&c.Subsystem))
+	lookup(EnvReportType, func(v string) error { return c.ReportType.UnmarshalText([]byte(v)) })
+	lookup(EnvPort, num(&c.Port))
+	lookup(EnvPushAddr, str(&c.PushAddr))
+	lookup(EnvJobName, str(&c.JobName))
+	lookup(EnvPushInterval, dur(&c.PushInterval))
+	lookup(EnvFilePath, str(&c.FilePath))
+	lookup(EnvFileFormat, func(v string) error { return c.FileFormat.UnmarshalText([]byte(v)) })
+	lookup(EnvFlushInterval, dur(&c.FlushInterval))
+	lookup(EnvMaxSeries, num(&c.MaxSeries))
+	lookup(EnvMaxSeriesPerMetric, num(&c.MaxSeriesPerMetric))
+	lookup(EnvLabelMode, func(v string) error { return c.LabelMode.UnmarshalText([]byte(v)) })
+	lookup(EnvNameValidation, func(v string) error { return c.NameValidation.UnmarshalText([]byte(v)) })
+	lookup(EnvCatalogFiles, func(v string) error {
+		c.CatalogFiles = nil
+		for _, file := range strings.Split(v, ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				c.CatalogFiles = append(c.CatalogFiles, file)
+			}
+		}
+		return nil
+	})
+	lookup(EnvConstLabels, func(v string) error {
+		labels, err := parseLabelPairs(v)
+		if err != nil {
+			return err
+		}
+		c.mergeConstLabels(labels)
+		return nil
+	})
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid environment variables: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// mergeConstLabels 将 labels 合并到常量标签中，同名时覆盖
+func (c *MetricsConfig) mergeConstLabels(labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	if c.ConstLabels == nil {
+		c.ConstLabels = make(map[string]string, len(labels))
+	}
+	for k, v := range labels {
+		c.ConstLabels[k] = v
+	}
+}
+
+// parseLabelPairs 解析 "k1=v1,k2=v2" 格式的标签
+func parseLabelPairs(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid label pair %q, expected key=value", pair)
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
+
+func setValue[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -11,10 +13,191 @@ type ReportType int
 const (
 	CollectorType ReportType = iota
 	PushgatewayType
+	FileType
 )
 
+var reportTypeNames = map[ReportType]string{
+	CollectorType:   "collector",
+	PushgatewayType: "pushgateway",
+	FileType:        "file",
+}
+
+func (t ReportType) String() string {
+	if name, ok := reportTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("ReportType(%d)", int(t))
+}
+
+// MarshalText 实现 encoding.TextMarshaler
+func (t ReportType) MarshalText() ([]byte, error) {
+	if _, ok := reportTypeNames[t]; !ok {
+		return nil, fmt.Errorf("invalid report type: %d", int(t))
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (t *ReportType) UnmarshalText(text []byte) error {
+	return parseEnum(reportTypeNames, "report type", string(text), t)
+}
+
+// FileFormat 定义了 File 模式下的输出格式
+type FileFormat int
+
+const (
+	FormatText        FileFormat = iota // Prometheus 文本格式
+	FormatOpenMetrics                   // OpenMetrics 文本格式
+	FormatJSONLines                     // 每行一条时间序列的 JSON
+)
+
+var fileFormatNames = map[FileFormat]string{
+	FormatText:        "text",
+	FormatOpenMetrics: "openmetrics",
+	FormatJSONLines:   "jsonl",
+}
+
+func (f FileFormat) String() string {
+	if name, ok := fileFormatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("FileFormat(%d)", int(f))
+}
+
+// MarshalText 实现 encoding.TextMarshaler
+func (f FileFormat) MarshalText() ([]byte, error) {
+	if _, ok := fileFormatNames[f]; !ok {
+		return nil, fmt.Errorf("invalid file format: %d", int(f))
+	}
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (f *FileFormat) UnmarshalText(text []byte) error {
+	return parseEnum(fileFormatNames, "file format", string(text), f)
+}
+
+// LabelMode 定义了上报时标签与 MetricInfo.Labels 不一致时的处理方式
+type LabelMode int
+
+const (
+	LabelModeLenient LabelMode = iota // 缺失的标签使用默认值填充，未声明的标签直接丢弃
+	LabelModeStrict                   // 缺失（且没有默认值）或未声明的标签会导致上报失败并返回错误
+)
+
+var labelModeNames = map[LabelMode]string{
+	LabelModeLenient: "lenient",
+	LabelModeStrict:  "strict",
+}
+
+func (m LabelMode) String() string {
+	if name, ok := labelModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("LabelMode(%d)", int(m))
+}
+
+// MarshalText 实现 encoding.TextMarshaler
+func (m LabelMode) MarshalText() ([]byte, error) {
+	if _, ok := labelModeNames[m]; !ok {
+		return nil, fmt.Errorf("invalid label mode: %d", int(m))
+	}
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (m *LabelMode) UnmarshalText(text []byte) error {
+	return parseEnum(labelModeNames, "label mode", string(text), m)
+}
+
+// DropPolicy 定义了异步模式下队列已满时的处理方式
+type DropPolicy int
+
+const (
+	DropPolicyNewest DropPolicy = iota // 丢弃新的上报
+	DropPolicyOldest                   // 丢弃队列中最旧的上报
+	DropPolicyBlock                    // 阻塞直到队列有空位
+)
+
+var dropPolicyNames = map[DropPolicy]string{
+	DropPolicyNewest: "newest",
+	DropPolicyOldest: "oldest",
+	DropPolicyBlock:  "block",
+}
+
+func (p DropPolicy) String() string {
+	if name, ok := dropPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("DropPolicy(%d)", int(p))
+}
+
+// MarshalText 实现 encoding.TextMarshaler
+func (p DropPolicy) MarshalText() ([]byte, error) {
+	if _, ok := dropPolicyNames[p]; !ok {
+		return nil, fmt.Errorf("invalid drop policy: %d", int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (p *DropPolicy) UnmarshalText(text []byte) error {
+	return parseEnum(dropPolicyNames, "drop policy", string(text), p)
+}
+
+// NameValidation 定义了指标名与标签名不符合 Prometheus 命名规范时的处理方式
+type NameValidation int
+
+const (
+	NameValidationWarn   NameValidation = iota // 仅记录警告日志
+	NameValidationReject                       // 拒绝注册并返回错误
+	NameValidationFix                          // 自动修正，如替换非法字符、补充单位与 _total 后缀
+)
+
+var nameValidationNames = map[NameValidation]string{
+	NameValidationWarn:   "warn",
+	NameValidationReject: "reject",
+	NameValidationFix:    "fix",
+}
+
+func (v NameValidation) String() string {
+	if name, ok := nameValidationNames[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("NameValidation(%d)", int(v))
+}
+
+// MarshalText 实现 encoding.TextMarshaler
+func (v NameValidation) MarshalText() ([]byte, error) {
+	if _, ok := nameValidationNames[v]; !ok {
+		return nil, fmt.Errorf("invalid name validation mode: %d", int(v))
+	}
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (v *NameValidation) UnmarshalText(text []byte) error {
+	return parseEnum(nameValidationNames, "name validation mode", string(text), v)
+}
+
+// parseEnum 按名称（忽略大小写）解析枚举值
+func parseEnum[T comparable](names map[T]string, kind, text string, out *T) error {
+	for value, name := range names {
+		if strings.EqualFold(name, strings.TrimSpace(text)) {
+			*out = value
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s: %q", kind, text)
+}
+
 // MetricsConfig 包含所有配置选项
 type MetricsConfig struct {
+	// ConfigFile 是 YAML 或 JSON 配置文件的路径，优先级：默认值 < 配置文件 < 环境变量 < Option
+	ConfigFile string
+	// CatalogFiles 是 YAML 格式的指标目录文件，其中的指标会在 Init 时自动注册
+	CatalogFiles []string
+
 	ReportType   ReportType
 	Namespace    string
 	Subsystem    string
@@ -22,8 +205,52 @@ type MetricsConfig struct {
 	PushAddr     string
 	JobName      string
 	PushInterval time.Duration
+
+	// File 模式
+	FilePath      string // 为空或 "-" 时输出到标准输出
+	FileFormat    FileFormat
+	FlushInterval time.Duration // <= 0 时仅在 Close 时写出
+
+	// ConstLabels 会作为常量标签附加到所有指标上（Pushgateway 模式下改为作为 grouping key）
+	ConstLabels map[string]string
+
+	// 基数限制，0 表示不限制；超出限制的标签组合会被折叠为 OverflowValue
+	MaxSeries          int    // 所有指标的时间序列总数上限
+	MaxSeriesPerMetric int    // 单个指标的默认时间序列上限，可被 MetricInfo.MaxSeries 覆盖
+	OverflowValue      string // 折叠后的标签值，默认为 DefaultOverflowValue
+
+	LabelMode      LabelMode
+	NameValidation NameValidation
+
+	// Summary 的默认配置，可被 MetricInfo 中的同名字段覆盖，0 表示使用 Prometheus 的默认值
+	SummaryMaxAge     time.Duration // 分位数的统计窗口，Prometheus 默认为 10 分钟
+	SummaryAgeBuckets uint32        // 统计窗口被划分的桶数，Prometheus 默认为 5
+	SummaryBufCap     uint32        // 样本缓冲区大小，Prometheus 默认为 500
+
+	// 异步模式：上报先进入有界队列，由后台协程写入，Close 时会处理完队列中剩余的上报
+	Async           bool
+	AsyncQueueSize  int
+	AsyncWorkers    int
+	AsyncDropPolicy DropPolicy
+
+	// SweepInterval 是检查 MetricInfo.TTL 过期标签组合的间隔，<= 0 时使用默认值
+	SweepInterval time.Duration
+
+	// Debug 开启后每次上报都会输出 Debug 日志，仅用于排查问题，会显著增加上报的开销
+	Debug bool
+
+	// 标准指标
+	GoCollector        bool     // Go 运行时指标（GC、goroutine、内存等）
+	GoRuntimeMetrics   []string // 额外采集的 runtime/metrics 指标，正则匹配，如 "/sched/latencies:seconds"
+	ProcessCollector   bool     // 进程指标（CPU、内存、文件描述符等）
+	BuildInfoCollector bool     // go_build_info 以及 build_info 指标
+	Version            string   // build_info 的 version 标签，为空时使用模块版本
+	Commit             string   // build_info 的 commit 标签，为空时使用 vcs.revision
 }
 
+// DefaultOverflowValue 是超出基数限制时默认使用的标签值
+const DefaultOverflowValue = "__overflow__"
+
 // Validate 验证配置的有效性
 func (c *MetricsConfig) Validate() error {
 	if c.Namespace == "" {
@@ -32,6 +259,34 @@ func (c *MetricsConfig) Validate() error {
 	if c.Subsystem == "" {
 		return fmt.Errorf("subsystem cannot be empty")
 	}
+	if c.MaxSeries < 0 || c.MaxSeriesPerMetric < 0 {
+		return fmt.Errorf("series limits cannot be negative")
+	}
+	if c.LabelMode != LabelModeLenient && c.LabelMode != LabelModeStrict {
+		return fmt.Errorf("invalid label mode: %v", c.LabelMode)
+	}
+	if c.Async {
+		if c.AsyncQueueSize <= 0 {
+			return fmt.Errorf("async queue size must be positive")
+		}
+		if c.AsyncWorkers <= 0 {
+			return fmt.Errorf("async workers must be positive")
+		}
+		if _, ok := dropPolicyNames[c.AsyncDropPolicy]; !ok {
+			return fmt.Errorf("invalid drop policy: %v", c.AsyncDropPolicy)
+		}
+	}
+	if c.SummaryMaxAge < 0 {
+		return fmt.Errorf("summary max age cannot be negative")
+	}
+	if _, ok := nameValidationNames[c.NameValidation]; !ok {
+		return fmt.Errorf("invalid name validation mode: %v", c.NameValidation)
+	}
+	for _, rule := range c.GoRuntimeMetrics {
+		if _, err := regexp.Compile(rule); err != nil {
+			return fmt.Errorf("invalid go runtime metrics rule %q: %w", rule, err)
+		}
+	}
 	switch c.ReportType {
 	case CollectorType:
 		if c.Port <= 0 || c.Port > 65535 {
@@ -47,6 +302,12 @@ func (c *MetricsConfig) Validate() error {
 		if c.PushInterval <= 0 {
 			return fmt.Errorf("pushInterval must be positive")
 		}
+	case FileType:
+		switch c.FileFormat {
+		case FormatText, FormatOpenMetrics, FormatJSONLines:
+		default:
+			return fmt.Errorf("invalid file format: %v", c.FileFormat)
+		}
 	default:
 		return fmt.Errorf("invalid report type: %v", c.ReportType)
 	}
@@ -0,0 +1,27 @@
+package metric_info
+
+import "context"
+
+type contextLabelsKey struct{}
+
+// ContextWithLabels 返回携带 labels 的新上下文，与上层上下文中的标签合并，同名时以 labels 为准
+func ContextWithLabels(ctx context.Context, labels map[string]string) context.Context {
+	if len(labels) == 0 {
+		return ctx
+	}
+	parent := LabelsFromContext(ctx)
+	merged := make(map[string]string, len(parent)+len(labels))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, contextLabelsKey{}, merged)
+}
+
+// LabelsFromContext 返回上下文中携带的标签，返回值不可修改
+func LabelsFromContext(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(contextLabelsKey{}).(map[string]string)
+	return labels
+}
@@ -2,6 +2,14 @@ package metric_info
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -31,6 +39,38 @@ func (m MetricType) String() string {
 	}
 }
 
+// ParseMetricType 将 counter、gauge、histogram、summary 解析为 MetricType
+func ParseMetricType(s string) (MetricType, error) {
+	for _, t := range []MetricType{Counter, Gauge, Histogram, Summary} {
+		if strings.EqualFold(s, t.String()) {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown metric type %q", s)
+}
+
+// MarshalText 实现 encoding.TextMarshaler
+func (m MetricType) MarshalText() ([]byte, error) {
+	if m.String() == "unknown" {
+		return nil, fmt.Errorf("unknown metric type %d", int(m))
+	}
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (m *MetricType) UnmarshalText(text []byte) (err error) {
+	*m, err = ParseMetricType(string(text))
+	return err
+}
+
+// 常用的时间单位，用于 MetricInfo.Unit
+const (
+	UnitSeconds      = "seconds"
+	UnitMilliseconds = "milliseconds"
+	UnitMicroseconds = "microseconds"
+	UnitNanoseconds  = "nanoseconds"
+)
+
 type MetricName string
 
 func (m MetricName) String() string {
@@ -48,21 +88,130 @@ type MetricInfo struct {
 	Help       string              // 指标帮助信息
 	Buckets    []float64           // 仅对Histogram有效
 	Objectives map[float64]float64 // 仅对Summary有效
+	MaxAge     time.Duration       // 仅对Summary有效，分位数的统计窗口，0 表示使用全局配置
+	AgeBuckets uint32              // 仅对Summary有效，统计窗口被划分的桶数，0 表示使用全局配置
+	BufCap     uint32              // 仅对Summary有效，样本缓冲区大小，0 表示使用全局配置
+	Unit       string              // 指标单位，如 seconds、bytes，可为空
+
+	// LegacyCounterName 为 true 时计数器名不要求 _total 后缀，仅用于兼容已有的指标名（如中间件的 req_cnt）
+	LegacyCounterName bool
+
+	// 常量标签「可选」，与全局常量标签合并，同名时以此处为准
+	ConstLabels map[string]string
 
 	// 标签「必选」
-	Labels       []string
-	LabelHandler map[string]LabelHandler
+	Labels        []string
+	LabelHandler  map[string]LabelHandler
+	LabelDefaults map[string]string // 上报时缺失标签的默认值，未设置时宽松模式下使用空字符串
+
+	// 基数控制「可选」
+	MaxSeries       int                        // 时间序列上限，0 表示使用全局配置
+	LabelAllowlist  map[string][]string        // 标签值白名单，不在名单内的值会被折叠
+	LabelNormalizer map[string]LabelNormalizer // 标签值规整函数，如转小写、截断等
+
+	// TTL「可选」，标签组合超过该时长未上报时会被删除，0 表示永不过期
+	TTL time.Duration
 }
 
 // LabelHandler 定义为一个函数类型，接收上下文，返回标签值
 type LabelHandler func(ctx context.Context) string
 
+// LabelNormalizer 对标签值进行规整，必须是幂等的
+type LabelNormalizer func(value string) string
+
+// NormalizeLabel 使用对应的 LabelNormalizer 规整标签值
+func (mi *MetricInfo) NormalizeLabel(name, value string) string {
+	if normalizer, ok := mi.LabelNormalizer[name]; ok {
+		return normalizer(value)
+	}
+	return value
+}
+
 // ToConstrainableLabels 将 LabelHandler 转换为 Prometheus 的 ConstrainableLabels
 func (mi *MetricInfo) ToConstrainableLabels() (ret prometheus.ConstrainedLabels) {
 	for _, name := range mi.Labels {
 		ret = append(ret, prometheus.ConstrainedLabel{
-			Name: name,
+			Name:       name,
+			Constraint: prometheus.LabelConstraint(mi.LabelNormalizer[name]),
 		})
 	}
 	return ret
 }
+
+// DurationValue 按照 Unit 将时长转换为上报值，Unit 不是时间单位时按秒计算
+func (mi *MetricInfo) DurationValue(d time.Duration) float64 {
+	switch mi.Unit {
+	case UnitMilliseconds:
+		return float64(d) / float64(time.Millisecond)
+	case UnitMicroseconds:
+		return float64(d) / float64(time.Microsecond)
+	case UnitNanoseconds:
+		return float64(d)
+	default:
+		return d.Seconds()
+	}
+}
+
+// ValidateObjectives 校验 Summary 的分位数在 (0,1) 之间，且误差为正并且不超出 [0,1] 的范围
+func (mi *MetricInfo) ValidateObjectives() error {
+	quantiles := make([]float64, 0, len(mi.Objectives))
+	for q := range mi.Objectives {
+		quantiles = append(quantiles, q)
+	}
+	sort.Float64s(quantiles)
+
+	for _, q := range quantiles {
+		e := mi.Objectives[q]
+		if q <= 0 || q >= 1 || math.IsNaN(q) {
+			return fmt.Errorf("objective quantile %v of [%s] must be in (0, 1)", q, mi.Name)
+		}
+		if e <= 0 || e > math.Min(q, 1-q) || math.IsNaN(e) {
+			return fmt.Errorf("objective error %v for quantile %v of [%s] must be in (0, %.6g]", e, q, mi.Name, math.Min(q, 1-q))
+		}
+	}
+	return nil
+}
+
+// Clone 返回 MetricInfo 的深拷贝，修改拷贝的 Labels、LabelHandler 等字段不会影响原指标
+func (mi *MetricInfo) Clone() *MetricInfo {
+	clone := *mi
+	clone.Buckets = slices.Clone(mi.Buckets)
+	clone.Objectives = maps.Clone(mi.Objectives)
+	clone.ConstLabels = maps.Clone(mi.ConstLabels)
+	clone.Labels = slices.Clone(mi.Labels)
+	clone.LabelHandler = maps.Clone(mi.LabelHandler)
+	clone.LabelDefaults = maps.Clone(mi.LabelDefaults)
+	clone.LabelNormalizer = maps.Clone(mi.LabelNormalizer)
+	if mi.LabelAllowlist != nil {
+		clone.LabelAllowlist = make(map[string][]string, len(mi.LabelAllowlist))
+		for label, values := range mi.LabelAllowlist {
+			clone.LabelAllowlist[label] = slices.Clone(values)
+		}
+	}
+	return &clone
+}
+
+// SameDefinition 判断两个 MetricInfo 是否描述同一个指标。函数无法比较，LabelHandler 与 LabelNormalizer
+// 只比较设置了函数的标签集合，集合相同时视为同一定义，重复注册会沿用最先注册的函数
+func (mi *MetricInfo) SameDefinition(other MetricInfo) bool {
+	if !sameKeys(mi.LabelHandler, other.LabelHandler) || !sameKeys(mi.LabelNormalizer, other.LabelNormalizer) {
+		return false
+	}
+	a, b := *mi, other
+	a.LabelHandler, b.LabelHandler = nil, nil
+	a.LabelNormalizer, b.LabelNormalizer = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// sameKeys 判断两个 map 的键集合是否相同
+func sameKeys[V any](a, b map[string]V) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
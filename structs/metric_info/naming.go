@@ -0,0 +1,144 @@
+package metric_info
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// CheckNames 按照 Prometheus 的命名规范检查指标名与标签名，返回发现的所有问题
+func (mi *MetricInfo) CheckNames() (problems []string) {
+	name := mi.Name.String()
+	switch {
+	case !metricNameRE.MatchString(name):
+		problems = append(problems, fmt.Sprintf("metric name %q contains invalid characters", name))
+	case strings.HasPrefix(name, "__"):
+		problems = append(problems, fmt.Sprintf("metric name %q uses the reserved prefix \"__\"", name))
+	}
+
+	base := name
+	if mi.Type == Counter && !mi.LegacyCounterName {
+		if !strings.HasSuffix(name, "_total") {
+			problems = append(problems, fmt.Sprintf("counter name %q should end with \"_total\"", name))
+		}
+		base = strings.TrimSuffix(name, "_total")
+	}
+	if mi.Unit != "" && !strings.HasSuffix(base, "_"+mi.Unit) {
+		problems = append(problems, fmt.Sprintf("metric name %q should end with the unit suffix \"_%s\"", name, mi.Unit))
+	}
+
+	for _, label := range mi.Labels {
+		problems = append(problems, mi.checkLabelName(label)...)
+	}
+	for label := range mi.ConstLabels {
+		problems = append(problems, mi.checkLabelName(label)...)
+	}
+	return problems
+}
+
+func (mi *MetricInfo) checkLabelName(label string) (problems []string) {
+	switch {
+	case !labelNameRE.MatchString(label):
+		problems = append(problems, fmt.Sprintf("label name %q contains invalid characters", label))
+	case strings.HasPrefix(label, "__"):
+		problems = append(problems, fmt.Sprintf("label name %q uses the reserved prefix \"__\"", label))
+	case label == "le" && mi.Type == Histogram, label == "quantile" && mi.Type == Summary:
+		problems = append(problems, fmt.Sprintf("label name %q is reserved for %s", label, mi.Type))
+	}
+	return problems
+}
+
+// FixNames 返回按照命名规范修正后的 MetricInfo，以及被修改的标签名（原名 -> 新名）
+func (mi *MetricInfo) FixNames() (fixed MetricInfo, renames map[string]string) {
+	fixed = *mi
+
+	name := sanitizeName(mi.Name.String(), true)
+	counter := mi.Type == Counter && !mi.LegacyCounterName
+	if counter {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	if mi.Unit != "" && !strings.HasSuffix(name, "_"+mi.Unit) {
+		name += "_" + mi.Unit
+	}
+	if counter {
+		name += "_total"
+	}
+	fixed.Name = NewMetricName(name)
+
+	renames = make(map[string]string)
+	fixLabel := func(label string) string {
+		ret := sanitizeName(label, false)
+		if (ret == "le" && mi.Type == Histogram) || (ret == "quantile" && mi.Type == Summary) {
+			ret += "_label"
+		}
+		if ret != label {
+			renames[label] = ret
+		}
+		return ret
+	}
+
+	fixed.Labels = make([]string, len(mi.Labels))
+	for i, label := range mi.Labels {
+		fixed.Labels[i] = fixLabel(label)
+	}
+	if len(mi.ConstLabels) > 0 {
+		fixed.ConstLabels = make(map[string]string, len(mi.ConstLabels))
+		for k, v := range mi.ConstLabels {
+			fixed.ConstLabels[fixLabel(k)] = v
+		}
+	}
+	if len(renames) == 0 {
+		return fixed, nil
+	}
+
+	fixed.LabelHandler = renameKeys(mi.LabelHandler, renames)
+	fixed.LabelDefaults = renameKeys(mi.LabelDefaults, renames)
+	fixed.LabelAllowlist = renameKeys(mi.LabelAllowlist, renames)
+	fixed.LabelNormalizer = renameKeys(mi.LabelNormalizer, renames)
+	return fixed, renames
+}
+
+// sanitizeName 将非法字符替换为下划线，并去掉保留的 "__" 前缀
+func sanitizeName(name string, allowColon bool) string {
+	var b strings.Builder
+	for i, r := range name {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(i > 0 && r >= '0' && r <= '9') || (allowColon && r == ':')
+		if !valid {
+			if i == 0 && r >= '0' && r <= '9' {
+				b.WriteByte('_')
+				b.WriteRune(r)
+				continue
+			}
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	ret := b.String()
+	for strings.HasPrefix(ret, "__") {
+		ret = ret[1:]
+	}
+	if ret == "" {
+		ret = "_"
+	}
+	return ret
+}
+
+func renameKeys[V any](m map[string]V, renames map[string]string) map[string]V {
+	if m == nil {
+		return nil
+	}
+	ret := make(map[string]V, len(m))
+	for k, v := range m {
+		if renamed, ok := renames[k]; ok {
+			k = renamed
+		}
+		ret[k] = v
+	}
+	return ret
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/everfir/metrics-go/structs/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrQueueFull 表示异步模式下队列已满，本次上报被丢弃
+var ErrQueueFull = errors.New("metrics queue full")
+
+// reportRequest 是一次排队中的上报，标签已经在入队前解析完毕
+type reportRequest struct {
+	wrapper *metricWrapper
+	values  []string // 按照 Labels 的声明顺序，已规整
+	value   float64
+	add     bool // 是否为 Add 操作
+}
+
+// asyncQueue 是一个有界队列，由后台协程消费并真正写入 Prometheus。
+// 每个消费协程有自己的 channel，同一时间序列的上报总是进入同一个 channel，因此按照调用顺序写入
+type asyncQueue struct {
+	chs    []chan reportRequest
+	policy config.DropPolicy
+	apply  func(reportRequest)
+
+	mu     sync.RWMutex // 保护 closed，避免向已关闭的 channel 发送
+	closed bool
+	wg     sync.WaitGroup
+
+	depth   prometheus.GaugeFunc
+	dropped prometheus.Counter
+}
+
+// newAsyncQueue 创建有 workers 个消费协程的队列，size 由各个协程平分
+func newAsyncQueue(namespace, subsystem string, size, workers int, policy config.DropPolicy) *asyncQueue {
+	q := &asyncQueue{
+		chs:    make([]chan reportRequest, workers),
+		policy: policy,
+	}
+	for i := range q.chs {
+		q.chs[i] = make(chan reportRequest, (size+workers-1)/workers)
+	}
+	q.depth = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "metrics_async_queue_depth",
+		Help:      "异步上报队列中等待处理的上报数",
+	}, func() float64 {
+		depth := 0
+		for _, ch := range q.chs {
+			depth += len(ch)
+		}
+		return float64(depth)
+	})
+	q.dropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "metrics_async_dropped_total",
+		Help:      "因异步上报队列已满被丢弃的上报数",
+	})
+	return q
+}
+
+// start 为每个 channel 启动一个消费协程
+func (q *asyncQueue) start(apply func(reportRequest)) {
+	q.apply = apply
+	for _, ch := range q.chs {
+		q.wg.Add(1)
+		go func(ch chan reportRequest) {
+			defer q.wg.Done()
+			for req := range ch {
+				apply(req)
+			}
+		}(ch)
+	}
+}
+
+// enqueue 按照丢弃策略将上报放入标签值对应的 channel。
+// Add 的增量丢失后仪表会永久偏移（如进行中请求数），因此 Add 从不丢弃：队列已满时阻塞等待，
+// DropPolicyOldest 挤出的最旧上报如果是 Add，则在调用方的协程中直接写入
+func (q *asyncQueue) enqueue(req reportRequest) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		q.dropped.Inc()
+		return ErrQueueFull
+	}
+
+	ch := q.chs[hashValues(req.values)%uint64(len(q.chs))]
+	if req.add || q.policy == config.DropPolicyBlock {
+		ch <- req
+		return nil
+	}
+	switch q.policy {
+	case config.DropPolicyOldest:
+		for {
+			select {
+			case ch <- req:
+				return nil
+			default:
+			}
+			// 丢弃队首最旧的一条后重试
+			select {
+			case old := <-ch:
+				if old.add {
+					q.apply(old)
+				} else {
+					q.dropped.Inc()
+				}
+			default:
+			}
+		}
+	default:
+		select {
+		case ch <- req:
+			return nil
+		default:
+			q.dropped.Inc()
+			return ErrQueueFull
+		}
+	}
+}
+
+// close 停止接收新的上报，并等待队列中剩余的上报处理完毕
+func (q *asyncQueue) close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		for _, ch := range q.chs {
+			close(ch)
+		}
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+}
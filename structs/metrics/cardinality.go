@@ -0,0 +1,247 @@
+package metrics
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/everfir/metrics-go/structs/metric_info"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	overflowReasonLimit     = "limit"
+	overflowReasonAllowlist = "allowlist"
+)
+
+// series 是一个标签组合对应的子指标，创建后即可在上报时直接使用，无需再经过 Vec 查找
+type series struct {
+	values   []string // 按照 Labels 的声明顺序，已规整
+	counter  prometheus.Counter
+	gauge    prometheus.Gauge
+	observer prometheus.Observer
+	lastSeen atomic.Int64 // UnixNano，仅在设置了 TTL 时更新
+}
+
+func newSeries(metric prometheus.Collector, values []string) *series {
+	s := &series{values: values}
+	switch vec := metric.(type) {
+	case *prometheus.CounterVec:
+		s.counter = vec.WithLabelValues(values...)
+	case *prometheus.GaugeVec:
+		s.gauge = vec.WithLabelValues(values...)
+	case *prometheus.HistogramVec:
+		s.observer = vec.WithLabelValues(values...)
+	case *prometheus.SummaryVec:
+		s.observer = vec.WithLabelValues(values...)
+	}
+	return s
+}
+
+// apply 按照指标类型处理上报的值：计数器累加、仪表设置、直方图与摘要观察
+func (s *series) apply(value float64) {
+	switch {
+	case s.counter != nil:
+		s.counter.Add(value)
+	case s.gauge != nil:
+		s.gauge.Set(value)
+	case s.observer != nil:
+		s.observer.Observe(value)
+	}
+}
+
+// add 对计数器或仪表累加 delta，调用方需保证指标类型支持
+func (s *series) add(delta float64) {
+	switch {
+	case s.counter != nil:
+		s.counter.Add(delta)
+	case s.gauge != nil:
+		s.gauge.Add(delta)
+	}
+}
+
+// seriesCache 以标签值的哈希为键缓存一个指标的所有子指标，同时承担基数限制与过期记录
+type seriesCache struct {
+	mu     sync.RWMutex
+	series map[uint64][]*series // 哈希冲突时在切片中逐个比较
+	count  int
+	limit  int
+	ttl    time.Duration
+
+	allowlist []map[string]struct{} // 按照 Labels 的声明顺序，nil 表示该标签不限制
+}
+
+func newSeriesCache(info metric_info.MetricInfo, defaultLimit int) *seriesCache {
+	c := &seriesCache{
+		series: make(map[uint64][]*series),
+		limit:  info.MaxSeries,
+		ttl:    info.TTL,
+	}
+	if c.limit == 0 {
+		c.limit = defaultLimit
+	}
+	if len(info.LabelAllowlist) > 0 {
+		c.allowlist = make([]map[string]struct{}, len(info.Labels))
+		for i, label := range info.Labels {
+			values, ok := info.LabelAllowlist[label]
+			if !ok {
+				continue
+			}
+			allowed := make(map[string]struct{}, len(values))
+			for _, v := range values {
+				allowed[info.NormalizeLabel(label, v)] = struct{}{}
+			}
+			c.allowlist[i] = allowed
+		}
+	}
+	return c
+}
+
+// find 查找标签值对应的子指标，调用方需持有 c.mu
+func (c *seriesCache) find(hash uint64, values []string) *series {
+	for _, s := range c.series[hash] {
+		if slices.Equal(s.values, values) {
+			return s
+		}
+	}
+	return nil
+}
+
+// cardinalityLimiter 负责白名单过滤以及单指标、全局两级的时间序列数量限制
+type cardinalityLimiter struct {
+	maxSeries     int
+	defaultLimit  int
+	overflowValue string
+	total         atomic.Int64
+	overflows     *prometheus.CounterVec
+}
+
+func newCardinalityLimiter(namespace, subsystem string, maxSeries, defaultLimit int, overflowValue string) *cardinalityLimiter {
+	return &cardinalityLimiter{
+		maxSeries:     maxSeries,
+		defaultLimit:  defaultLimit,
+		overflowValue: overflowValue,
+		overflows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "metrics_series_overflow_total",
+			Help:      "因基数限制或标签白名单被折叠的上报次数",
+		}, []string{"metric", "reason"}),
+	}
+}
+
+// admit 对按声明顺序排列的标签值进行白名单过滤与基数限制，返回应当上报的子指标；
+// values 可能被就地改写为 overflowValue，命中缓存时不会产生内存分配
+func (l *cardinalityLimiter) admit(wrapper *metricWrapper, values []string) *series {
+	cache := wrapper.series
+	for i, allowed := range cache.allowlist {
+		if allowed == nil {
+			continue
+		}
+		if _, ok := allowed[values[i]]; !ok {
+			values[i] = l.overflowValue
+			l.overflows.WithLabelValues(wrapper.info.Name.String(), overflowReasonAllowlist).Inc()
+		}
+	}
+
+	hash := hashValues(values)
+	cache.mu.RLock()
+	s := cache.find(hash, values)
+	cache.mu.RUnlock()
+	if s == nil {
+		s = l.create(wrapper, hash, values)
+	}
+	if cache.ttl > 0 {
+		s.lastSeen.Store(time.Now().UnixNano())
+	}
+	return s
+}
+
+// create 在缓存未命中时创建子指标，超过限制时折叠到所有标签均为 overflowValue 的序列
+func (l *cardinalityLimiter) create(wrapper *metricWrapper, hash uint64, values []string) *series {
+	cache := wrapper.series
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if s := cache.find(hash, values); s != nil {
+		return s
+	}
+
+	overLimit := cache.limit > 0 && cache.count >= cache.limit
+	if !overLimit && l.maxSeries > 0 && l.total.Load() >= int64(l.maxSeries) {
+		overLimit = true
+	}
+	if overLimit {
+		values = make([]string, len(values))
+		for i := range values {
+			values[i] = l.overflowValue
+		}
+		l.overflows.WithLabelValues(wrapper.info.Name.String(), overflowReasonLimit).Inc()
+		hash = hashValues(values)
+		if s := cache.find(hash, values); s != nil {
+			return s
+		}
+		// 溢出序列本身不受限制，否则会丢失数据
+	} else {
+		// values 来自复用的缓冲区，需要复制一份
+		values = slices.Clone(values)
+	}
+
+	s := newSeries(wrapper.metric, values)
+	if cache.ttl > 0 {
+		// 在持有锁时初始化，否则清理协程可能在释放锁后、admit 更新之前把新建的子指标当作已过期
+		s.lastSeen.Store(time.Now().UnixNano())
+	}
+	cache.series[hash] = append(cache.series[hash], s)
+	cache.count++
+	l.total.Add(1)
+	return s
+}
+
+// forget 移除满足 match 的子指标并从 Vec 中删除，返回移除的数量；
+// 删除与创建都在 cache.mu 内完成，避免缓存中残留已被 Vec 删除的子指标
+func (l *cardinalityLimiter) forget(wrapper *metricWrapper, match func(s *series) bool) int {
+	cache := wrapper.series
+	deleter := wrapper.metric.(vecDeleter)
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	removed := 0
+	for hash, list := range cache.series {
+		kept := list[:0]
+		for _, s := range list {
+			if !match(s) {
+				kept = append(kept, s)
+				continue
+			}
+			deleter.DeleteLabelValues(s.values...)
+			removed++
+		}
+		if len(kept) == 0 {
+			delete(cache.series, hash)
+		} else {
+			cache.series[hash] = kept
+		}
+	}
+	cache.count -= removed
+	l.total.Add(-int64(removed))
+	return removed
+}
+
+// hashValues 使用 FNV-1a 计算标签值的哈希，标签值之间以 0xff 分隔
+func hashValues(values []string) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	hash := uint64(offset64)
+	for _, v := range values {
+		for i := 0; i < len(v); i++ {
+			hash ^= uint64(v[i])
+			hash *= prime64
+		}
+		hash ^= 0xff
+		hash *= prime64
+	}
+	return hash
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+	"github.com/everfir/metrics-go/structs/metric_info"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultSweepInterval 是检查过期标签组合的默认间隔
+const DefaultSweepInterval = 30 * time.Second
+
+// vecDeleter 是各类 Vec 指标共有的删除能力
+type vecDeleter interface {
+	DeleteLabelValues(lvs ...string) bool
+	DeletePartialMatch(labels prometheus.Labels) int
+}
+
+// DeleteLabelValues 删除指定标签值（按照 Labels 的声明顺序）对应的时间序列
+func (pm *PrometheusMetrics) DeleteLabelValues(name metric_info.MetricName, values ...string) (bool, error) {
+	wrapper, exists := pm.getMetric(name)
+	if !exists {
+		return false, fmt.Errorf("%w: [%s]", ErrMetricNotFound, name)
+	}
+	if len(values) != len(wrapper.info.Labels) {
+		return false, fmt.Errorf("%w for metric [%s]: expected %d label values, got %d",
+			ErrInvalidLabels, name, len(wrapper.info.Labels), len(values))
+	}
+
+	normalized := make([]string, len(values))
+	for i, label := range wrapper.info.Labels {
+		normalized[i] = wrapper.info.NormalizeLabel(label, values[i])
+	}
+	removed := pm.limiter.forget(wrapper, func(s *series) bool {
+		return slices.Equal(s.values, normalized)
+	})
+	return removed > 0, nil
+}
+
+// DeletePartialMatch 删除包含指定标签（部分匹配）的所有时间序列，返回删除的数量
+func (pm *PrometheusMetrics) DeletePartialMatch(name metric_info.MetricName, labels map[string]string) (int, error) {
+	wrapper, exists := pm.getMetric(name)
+	if !exists {
+		return 0, fmt.Errorf("%w: [%s]", ErrMetricNotFound, name)
+	}
+
+	match := make(map[int]string, len(labels))
+	for label, value := range labels {
+		i, ok := wrapper.index[label]
+		if !ok {
+			return 0, fmt.Errorf("%w for metric [%s]: unknown label [%s]", ErrInvalidLabels, name, label)
+		}
+		match[i] = wrapper.info.NormalizeLabel(wrapper.info.Labels[i], value)
+	}
+
+	return pm.limiter.forget(wrapper, func(s *series) bool {
+		for i, value := range match {
+			if s.values[i] != value {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
+
+// startSweeper 在第一次注册带 TTL 的指标时启动后台清理协程，调用方需持有 pm.mu
+func (pm *PrometheusMetrics) startSweeper() {
+	if pm.sweeping {
+		return
+	}
+	pm.sweeping = true
+	go func() {
+		ticker := time.NewTicker(pm.sweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				pm.sweep(now)
+			case <-pm.done:
+				return
+			}
+		}
+	}()
+}
+
+// sweep 删除所有超过 TTL 未上报的标签组合
+func (pm *PrometheusMetrics) sweep(now time.Time) {
+	pm.mu.RLock()
+	wrappers := make([]*metricWrapper, 0, len(pm.metrics))
+	for _, wrapper := range pm.metrics {
+		if wrapper.series.ttl > 0 {
+			wrappers = append(wrappers, wrapper)
+		}
+	}
+	pm.mu.RUnlock()
+
+	for _, wrapper := range wrappers {
+		deadline := now.Add(-wrapper.series.ttl).UnixNano()
+		expired := pm.limiter.forget(wrapper, func(s *series) bool {
+			return s.lastSeen.Load() <= deadline
+		})
+		if expired > 0 {
+			logger.Debug(context.TODO(), "expired metric series removed",
+				field.String("name", wrapper.info.Name.String()),
+				field.Any("count", expired),
+			)
+		}
+	}
+}
+
+// Close 停止后台清理协程，异步模式下会等待队列中的上报全部处理完毕
+func (pm *PrometheusMetrics) Close() {
+	pm.closeOnce.Do(func() {
+		close(pm.done)
+		if pm.queue != nil {
+			pm.queue.close()
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/everfir/metrics-go/structs/config"
+	"github.com/everfir/metrics-go/structs/metric_info"
+)
+
+var (
+	// ErrMetricNotFound 表示上报的指标尚未注册
+	ErrMetricNotFound = errors.New("metric not found")
+	// ErrInvalidLabels 表示上报的标签与指标声明的标签不一致
+	ErrInvalidLabels = errors.New("invalid labels")
+	// ErrAlreadyRegistered 表示同名指标已经以不同的定义注册
+	ErrAlreadyRegistered = errors.New("metric already registered")
+	// ErrInvalidName 表示指标名或标签名不符合 Prometheus 的命名规范
+	ErrInvalidName = errors.New("invalid metric name")
+	// ErrUnsupportedOperation 表示指标类型不支持该操作，如对直方图调用 Add
+	ErrUnsupportedOperation = errors.New("unsupported operation")
+)
+
+// labelValues 是上报时按声明顺序排列的标签值，通过 labelValuesPool 复用以避免每次上报分配内存
+type labelValues struct {
+	values []string
+	set    []bool
+}
+
+var labelValuesPool = sync.Pool{New: func() any { return new(labelValues) }}
+
+func acquireLabelValues(n int) *labelValues {
+	lv := labelValuesPool.Get().(*labelValues)
+	if cap(lv.values) < n {
+		lv.values = make([]string, n)
+		lv.set = make([]bool, n)
+	}
+	lv.values = lv.values[:n]
+	lv.set = lv.set[:n]
+	return lv
+}
+
+func (lv *labelValues) release() {
+	clear(lv.values)
+	clear(lv.set)
+	labelValuesPool.Put(lv)
+}
+
+func (lv *labelValues) put(i int, value string) {
+	lv.values[i] = value
+	lv.set[i] = true
+}
+
+// indexedHandler 是按照声明顺序预先解析的 LabelHandler
+type indexedHandler struct {
+	index   int
+	handler metric_info.LabelHandler
+}
+
+// labelIndex 预先计算标签名（包括命名修正前的原名）到声明顺序的映射，
+// 以及 LabelHandler、LabelNormalizer 的位置，返回未声明却设置了 LabelHandler 的标签
+func labelIndex(info metric_info.MetricInfo, renames map[string]string) (index map[string]int, handlers []indexedHandler, normalizers []metric_info.LabelNormalizer, undeclared []string) {
+	index = make(map[string]int, len(info.Labels)+len(renames))
+	for i, label := range info.Labels {
+		index[label] = i
+	}
+	for from, to := range renames {
+		if i, ok := index[to]; ok {
+			index[from] = i
+		}
+	}
+
+	for label, handler := range info.LabelHandler {
+		if i, ok := index[label]; ok {
+			handlers = append(handlers, indexedHandler{index: i, handler: handler})
+		} else {
+			undeclared = append(undeclared, label)
+		}
+	}
+
+	for i, label := range info.Labels {
+		if normalizer, ok := info.LabelNormalizer[label]; ok {
+			if normalizers == nil {
+				normalizers = make([]metric_info.LabelNormalizer, len(info.Labels))
+			}
+			normalizers[i] = normalizer
+		}
+	}
+	return index, handlers, normalizers, undeclared
+}
+
+// resolve 依次合并 LabelHandler、上下文标签与显式标签，按照 mode 校验后写入 lv 并完成规整；
+// 宽松模式下缺失的标签使用默认值或空字符串，未声明的标签被忽略
+func (w *metricWrapper) resolve(ctx context.Context, labels map[string]string, mode config.LabelMode, lv *labelValues) error {
+	strict := mode == config.LabelModeStrict
+
+	for _, h := range w.handlers {
+		lv.put(h.index, h.handler(ctx))
+	}
+	// 上下文中的标签只合并指标声明了的
+	for k, v := range metric_info.LabelsFromContext(ctx) {
+		if i, ok := w.index[k]; ok {
+			lv.put(i, v)
+		}
+	}
+
+	var missing, unknown []string
+	if strict {
+		unknown = append(unknown, w.undeclared...)
+	}
+	for k, v := range labels {
+		if i, ok := w.index[k]; ok {
+			lv.put(i, v)
+		} else if strict {
+			unknown = append(unknown, k)
+		}
+	}
+	for i, label := range w.info.Labels {
+		if lv.set[i] {
+			continue
+		}
+		if value, ok := w.info.LabelDefaults[label]; ok {
+			lv.values[i] = value
+		} else if strict {
+			missing = append(missing, label)
+		}
+	}
+	if len(missing) > 0 || len(unknown) > 0 {
+		return labelError(w.info, missing, unknown)
+	}
+
+	for i, normalizer := range w.normalizers {
+		if normalizer != nil {
+			lv.values[i] = normalizer(lv.values[i])
+		}
+	}
+	return nil
+}
+
+func labelError(info metric_info.MetricInfo, missing, unknown []string) error {
+	sort.Strings(unknown)
+
+	var details []string
+	if len(missing) > 0 {
+		details = append(details, fmt.Sprintf("missing [%s]", strings.Join(missing, ", ")))
+	}
+	if len(unknown) > 0 {
+		details = append(details, fmt.Sprintf("unknown [%s]", strings.Join(unknown, ", ")))
+	}
+	return fmt.Errorf("%w for metric [%s]: %s, declared labels are [%s]",
+		ErrInvalidLabels, info.Name, strings.Join(details, ", "), strings.Join(info.Labels, ", "))
+}
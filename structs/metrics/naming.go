@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+	"github.com/everfir/metrics-go/structs/config"
+	"github.com/everfir/metrics-go/structs/metric_info"
+)
+
+// checkNames 按照 nameMode 检查指标名与标签名，修正模式下会就地修改 info 的标签，
+// 返回导出到 Prometheus 的指标名以及被修改的标签名
+func (pm *PrometheusMetrics) checkNames(info *metric_info.MetricInfo) (promName string, renames map[string]string, err error) {
+	problems := info.CheckNames()
+	if len(problems) == 0 {
+		return info.Name.String(), nil, nil
+	}
+
+	switch pm.nameMode {
+	case config.NameValidationReject:
+		return "", nil, fmt.Errorf("%w [%s]: %s", ErrInvalidName, info.Name, strings.Join(problems, "; "))
+	case config.NameValidationFix:
+		fixed, renames := info.FixNames()
+		logger.Warn(context.TODO(), "metric names fixed",
+			field.String("name", info.Name.String()),
+			field.String("fixed", fixed.Name.String()),
+			field.String("problems", strings.Join(problems, "; ")),
+		)
+		promName = fixed.Name.String()
+		fixed.Name = info.Name
+		*info = fixed
+		return promName, renames, nil
+	default:
+		logger.Warn(context.TODO(), "metric names do not follow Prometheus conventions",
+			field.String("name", info.Name.String()),
+			field.String("problems", strings.Join(problems, "; ")),
+		)
+		return info.Name.String(), nil, nil
+	}
+}
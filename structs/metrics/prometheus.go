@@ -3,60 +3,182 @@ package metrics
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/everfir/logger-go"
 	"github.com/everfir/logger-go/structs/field"
+	"github.com/everfir/metrics-go/structs/config"
 	"github.com/everfir/metrics-go/structs/metric_info"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 // PrometheusMetrics 实现了MetricsInterface，使用Prometheus Go SDK
 type PrometheusMetrics struct {
-	namespace string
-	subsystem string
+	namespace   string
+	subsystem   string
+	constLabels map[string]string
 
 	registry *prometheus.Registry
-	metrics  map[metric_info.MetricName]metricWrapper
+	metrics  map[metric_info.MetricName]*metricWrapper
 	mu       sync.RWMutex
+
+	summary summaryDefaults
+
+	limiter   *cardinalityLimiter
+	labelMode config.LabelMode
+	nameMode  config.NameValidation
+	queue     *asyncQueue // 未开启异步模式时为 nil
+
+	// 过期清理
+	sweepInterval time.Duration
+	sweeping      bool
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// summaryDefaults 是 MetricInfo 未指定时 Summary 使用的默认配置
+type summaryDefaults struct {
+	maxAge     time.Duration
+	ageBuckets uint32
+	bufCap     uint32
 }
 
 type metricWrapper struct {
-	metric prometheus.Collector
-	info   metric_info.MetricInfo
+	metric  prometheus.Collector
+	info    metric_info.MetricInfo // 命名修正后的定义，Name 保持不变
+	def     metric_info.MetricInfo // 注册时传入的原始定义
+	renames map[string]string      // 命名修正时被修改的标签名（原名 -> 新名）
+	series  *seriesCache           // 已创建的子指标
+
+	// 注册时预先计算，上报时按照声明顺序直接填充标签值
+	index       map[string]int                // 标签名（包括修正前的原名）到声明顺序的映射
+	handlers    []indexedHandler              // 声明了的标签对应的 LabelHandler
+	normalizers []metric_info.LabelNormalizer // 按照声明顺序，未设置规整函数时为 nil
+	undeclared  []string                      // 未声明却设置了 LabelHandler 的标签
 }
 
-// New 创建一个新的PrometheusMetrics实例
+// New 创建一个新的PrometheusMetrics实例，其余配置使用默认值
 func New(namespace, subsystem string) *PrometheusMetrics {
-	return &PrometheusMetrics{
-		namespace: namespace,
-		subsystem: subsystem,
-		registry:  prometheus.NewRegistry(),
-		metrics:   make(map[metric_info.MetricName]metricWrapper),
-		mu:        sync.RWMutex{},
+	return NewWithConfig(&config.MetricsConfig{Namespace: namespace, Subsystem: subsystem})
+}
+
+// NewWithConfig 根据配置创建一个新的PrometheusMetrics实例
+func NewWithConfig(cfg *config.MetricsConfig) *PrometheusMetrics {
+	overflowValue := cfg.OverflowValue
+	if overflowValue == "" {
+		overflowValue = config.DefaultOverflowValue
+	}
+	sweepInterval := cfg.SweepInterval
+	if sweepInterval <= 0 {
+		sweepInterval = DefaultSweepInterval
+	}
+
+	pm := &PrometheusMetrics{
+		namespace:   cfg.Namespace,
+		subsystem:   cfg.Subsystem,
+		constLabels: cfg.ConstLabels,
+		registry:    prometheus.NewRegistry(),
+		metrics:     make(map[metric_info.MetricName]*metricWrapper),
+		mu:          sync.RWMutex{},
+		limiter:     newCardinalityLimiter(cfg.Namespace, cfg.Subsystem, cfg.MaxSeries, cfg.MaxSeriesPerMetric, overflowValue),
+		labelMode:   cfg.LabelMode,
+		nameMode:    cfg.NameValidation,
+
+		summary: summaryDefaults{
+			maxAge:     cfg.SummaryMaxAge,
+			ageBuckets: cfg.SummaryAgeBuckets,
+			bufCap:     cfg.SummaryBufCap,
+		},
+
+		sweepInterval: sweepInterval,
+		done:          make(chan struct{}),
+	}
+	pm.registry.MustRegister(pm.limiter.overflows)
+	pm.registerRuntimeCollectors(cfg)
+
+	if cfg.Async {
+		pm.queue = newAsyncQueue(cfg.Namespace, cfg.Subsystem, cfg.AsyncQueueSize, cfg.AsyncWorkers, cfg.AsyncDropPolicy)
+		pm.registry.MustRegister(pm.queue.depth, pm.queue.dropped)
+		pm.queue.start(func(req reportRequest) {
+			pm.apply(req.wrapper, req.values, req.value, req.add)
+		})
+	}
+	return pm
+}
+
+func (d summaryDefaults) maxAgeFor(info metric_info.MetricInfo) time.Duration {
+	if info.MaxAge > 0 {
+		return info.MaxAge
+	}
+	return d.maxAge
+}
+
+func (d summaryDefaults) ageBucketsFor(info metric_info.MetricInfo) uint32 {
+	if info.AgeBuckets > 0 {
+		return info.AgeBuckets
+	}
+	return d.ageBuckets
+}
+
+func (d summaryDefaults) bufCapFor(info metric_info.MetricInfo) uint32 {
+	if info.BufCap > 0 {
+		return info.BufCap
+	}
+	return d.bufCap
+}
+
+// constLabelsFor 合并全局与指标自身的常量标签，指标自身的优先
+func (pm *PrometheusMetrics) constLabelsFor(info metric_info.MetricInfo) prometheus.Labels {
+	if len(pm.constLabels) == 0 && len(info.ConstLabels) == 0 {
+		return nil
+	}
+	labels := make(prometheus.Labels, len(pm.constLabels)+len(info.ConstLabels))
+	for k, v := range pm.constLabels {
+		labels[k] = v
+	}
+	for k, v := range info.ConstLabels {
+		labels[k] = v
 	}
+	return labels
 }
 
-// Register 根据MetricInfo自动注册指标
-func (pm *PrometheusMetrics) Register(info metric_info.MetricInfo) {
+// Register 根据MetricInfo自动注册指标，重复注册相同定义的指标时直接复用已有指标，定义冲突时返回错误
+func (pm *PrometheusMetrics) Register(info metric_info.MetricInfo) error {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	// 不能重复注册
-	if _, exists := pm.metrics[info.Name]; exists {
-		panic(fmt.Sprintf("[metrics] metric [%s] already registered with a different type", info.Name))
+	if existing, exists := pm.metrics[info.Name]; exists {
+		if existing.def.SameDefinition(info) {
+			return nil
+		}
+		return fmt.Errorf("%w: [%s] conflicts with the existing definition", ErrAlreadyRegistered, info.Name)
 	}
 
+	def := info
+	promName, renames, err := pm.checkNames(&info)
+	if err != nil {
+		return err
+	}
+	if info.Type == metric_info.Summary {
+		if err := info.ValidateObjectives(); err != nil {
+			return err
+		}
+	}
+
+	constLabels := pm.constLabelsFor(info)
 	var metric prometheus.Collector
 	switch info.Type {
 	case metric_info.Counter:
 		metric = prometheus.V2.NewCounterVec(
 			prometheus.CounterVecOpts{
 				CounterOpts: prometheus.CounterOpts{
-					Namespace: pm.namespace,
-					Subsystem: pm.subsystem,
-					Name:      info.Name.String(),
-					Help:      info.Help,
+					Namespace:   pm.namespace,
+					Subsystem:   pm.subsystem,
+					Name:        promName,
+					Help:        info.Help,
+					ConstLabels: constLabels,
 				},
 				VariableLabels: info.ToConstrainableLabels(),
 			},
@@ -65,10 +187,11 @@ func (pm *PrometheusMetrics) Register(info metric_info.MetricInfo) {
 		metric = prometheus.V2.NewGaugeVec(
 			prometheus.GaugeVecOpts{
 				GaugeOpts: prometheus.GaugeOpts{
-					Namespace: pm.namespace,
-					Subsystem: pm.subsystem,
-					Name:      info.Name.String(),
-					Help:      info.Help,
+					Namespace:   pm.namespace,
+					Subsystem:   pm.subsystem,
+					Name:        promName,
+					Help:        info.Help,
+					ConstLabels: constLabels,
 				},
 				VariableLabels: info.ToConstrainableLabels(),
 			},
@@ -77,11 +200,12 @@ func (pm *PrometheusMetrics) Register(info metric_info.MetricInfo) {
 		metric = prometheus.V2.NewHistogramVec(
 			prometheus.HistogramVecOpts{
 				HistogramOpts: prometheus.HistogramOpts{
-					Namespace: pm.namespace,
-					Subsystem: pm.subsystem,
-					Name:      info.Name.String(),
-					Help:      info.Help,
-					Buckets:   info.Buckets,
+					Namespace:   pm.namespace,
+					Subsystem:   pm.subsystem,
+					Name:        promName,
+					Help:        info.Help,
+					ConstLabels: constLabels,
+					Buckets:     info.Buckets,
 				},
 				VariableLabels: info.ToConstrainableLabels(),
 			},
@@ -90,71 +214,118 @@ func (pm *PrometheusMetrics) Register(info metric_info.MetricInfo) {
 		metric = prometheus.V2.NewSummaryVec(
 			prometheus.SummaryVecOpts{
 				SummaryOpts: prometheus.SummaryOpts{
-					Namespace:  pm.namespace,
-					Subsystem:  pm.subsystem,
-					Name:       info.Name.String(),
-					Help:       info.Help,
-					Objectives: info.Objectives,
+					Namespace:   pm.namespace,
+					Subsystem:   pm.subsystem,
+					Name:        promName,
+					Help:        info.Help,
+					ConstLabels: constLabels,
+					Objectives:  info.Objectives,
+					MaxAge:      pm.summary.maxAgeFor(info),
+					AgeBuckets:  pm.summary.ageBucketsFor(info),
+					BufCap:      pm.summary.bufCapFor(info),
 				},
 				VariableLabels: info.ToConstrainableLabels(),
 			},
 		)
 	default:
-		panic(fmt.Sprintf("[metrics] unknown metric type for [%s]", info.Name))
+		return fmt.Errorf("unknown metric type for [%s]", info.Name)
+	}
+
+	if err := pm.registry.Register(metric); err != nil {
+		return fmt.Errorf("register metric [%s]: %w", info.Name, err)
+	}
+	wrapper := &metricWrapper{metric: metric, info: info, def: def, renames: renames}
+	wrapper.series = newSeriesCache(info, pm.limiter.defaultLimit)
+	wrapper.index, wrapper.handlers, wrapper.normalizers, wrapper.undeclared = labelIndex(info, renames)
+	pm.metrics[info.Name] = wrapper
+	if info.TTL > 0 {
+		pm.startSweeper()
+	}
+	return nil
+}
+
+// Unregister 注销指标，返回指标是否存在
+func (pm *PrometheusMetrics) Unregister(name metric_info.MetricName) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	wrapper, exists := pm.metrics[name]
+	if !exists {
+		return false
 	}
+	pm.registry.Unregister(wrapper.metric)
+	pm.limiter.forget(wrapper, func(*series) bool { return true })
+	delete(pm.metrics, name)
+	return true
+}
 
-	pm.registry.MustRegister(metric)
-	pm.metrics[info.Name] = metricWrapper{metric: metric, info: info}
+// Info 返回已注册指标的定义
+func (pm *PrometheusMetrics) Info(name metric_info.MetricName) (metric_info.MetricInfo, bool) {
+	wrapper, exists := pm.getMetric(name)
+	if !exists {
+		return metric_info.MetricInfo{}, false
+	}
+	return wrapper.def, true
 }
 
 // GetMetric 通过名字获取指标
-func (pm *PrometheusMetrics) getMetric(name metric_info.MetricName) (metricWrapper, bool) {
+func (pm *PrometheusMetrics) getMetric(name metric_info.MetricName) (*metricWrapper, bool) {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 	metric, exists := pm.metrics[name]
 	return metric, exists
 }
 
-// Report 上报数据
-func (pm *PrometheusMetrics) Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) {
+// Report 上报数据，标签不合法时根据 LabelMode 修正或返回错误；
+// 异步模式下标签在调用方的协程中解析，只有写入 Prometheus 在后台进行，队列已满时返回 ErrQueueFull
+func (pm *PrometheusMetrics) Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) error {
+	return pm.submit(ctx, name, labels, value, false)
+}
+
+// Add 对计数器或仪表累加 delta（仪表可以为负数），用于进行中请求数等需要增减的场景；
+// 直方图与摘要返回 ErrUnsupportedOperation，其余行为与 Report 相同
+func (pm *PrometheusMetrics) Add(ctx context.Context, name metric_info.MetricName, labels map[string]string, delta float64) error {
+	return pm.submit(ctx, name, labels, delta, true)
+}
+
+// submit 是上报的热路径：标签按照注册时预先计算的顺序填充到复用的缓冲区，
+// 子指标通过标签值的哈希从缓存中取得，同步模式下命中缓存时不会产生内存分配。
+// LabelHandler 与上下文标签总是在调用方的协程中求值，ctx 不会被保留（如 *gin.Context 在请求结束后会被复用）
+func (pm *PrometheusMetrics) submit(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64, add bool) error {
 	// 获取指标包装器
-	metricWrapper, exists := pm.getMetric(name)
+	wrapper, exists := pm.getMetric(name)
 	if !exists {
 		// 如果指标不存在，记录警告日志并返回
 		logger.Warn(ctx, "metric not found", field.String("name", name.String()))
-		return
+		return fmt.Errorf("%w: [%s]", ErrMetricNotFound, name)
+	}
+	if add && wrapper.info.Type != metric_info.Counter && wrapper.info.Type != metric_info.Gauge {
+		return fmt.Errorf("%w: cannot add to %s [%s]", ErrUnsupportedOperation, wrapper.info.Type, name)
 	}
 
-	// 创建标签映射
-	var mapping = map[string]string{}
-	// 处理预定义的标签处理函数
-	for k, v := range metricWrapper.info.LabelHandler {
-		mapping[k] = v(ctx)
+	lv := acquireLabelValues(len(wrapper.info.Labels))
+	defer lv.release()
+	// 合并并校验标签
+	if err := wrapper.resolve(ctx, labels, pm.labelMode, lv); err != nil {
+		logger.Warn(ctx, "invalid metric labels", field.String("name", name.String()), field.String("err", err.Error()))
+		return err
 	}
-	// 合并用户提供的标签
-	for k, v := range labels {
-		mapping[k] = v
+
+	if pm.queue == nil {
+		pm.apply(wrapper, lv.values, value, add)
+		return nil
 	}
+	// lv 会被复用，需要复制一份
+	return pm.queue.enqueue(reportRequest{wrapper: wrapper, values: slices.Clone(lv.values), value: value, add: add})
+}
 
-	// 根据指标类型进行不同的处理
-	switch metricWrapper.info.Type {
-	case metric_info.Counter:
-		// 对于计数器类型，增加指定的值
-		metricWrapper.metric.(*prometheus.CounterVec).With(mapping).Add(value)
-	case metric_info.Gauge:
-		// 对于仪表类型，设置指定的值
-		metricWrapper.metric.(*prometheus.GaugeVec).With(mapping).Set(value)
-	case metric_info.Histogram:
-		// 对于直方图类型，观察指定的值
-		metricWrapper.metric.(*prometheus.HistogramVec).With(mapping).Observe(value)
-	case metric_info.Summary:
-		// 对于摘要类型，观察指定的值
-		metricWrapper.metric.(*prometheus.SummaryVec).With(mapping).Observe(value)
-	default:
-		// 对于未知类型，记录错误日志
-		logger.Error(ctx, "未知的指标类型",
-			field.String("name", name.String()),
-			field.String("type", string(metricWrapper.info.Type)))
+// apply 对解析后的标签值进行白名单过滤、基数限制与过期记录，随后根据指标类型处理上报的值
+func (pm *PrometheusMetrics) apply(wrapper *metricWrapper, values []string, value float64, add bool) {
+	s := pm.limiter.admit(wrapper, values)
+	if add {
+		s.add(value)
+	} else {
+		s.apply(value)
 	}
 }
 
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/everfir/metrics-go/structs/metric_info"
+)
+
+type benchKey struct{}
+
+// BenchmarkReport 测量同步上报命中缓存时的开销，各种路径都应当为 0 allocs/op
+func BenchmarkReport(b *testing.B) {
+	pm := New("", "")
+	defer pm.Close()
+
+	register := func(info metric_info.MetricInfo) {
+		if err := pm.Register(info); err != nil {
+			b.Fatal(err)
+		}
+	}
+	register(metric_info.MetricInfo{Type: metric_info.Counter, Name: "counter_total", Help: "counter", Labels: []string{"method", "status"}})
+	register(metric_info.MetricInfo{Type: metric_info.Histogram, Name: "histogram", Help: "histogram", Labels: []string{"method"}})
+	register(metric_info.MetricInfo{
+		Type:   metric_info.Counter,
+		Name:   "handler_total",
+		Help:   "label handler",
+		Labels: []string{"method", "user"},
+		LabelHandler: map[string]metric_info.LabelHandler{
+			"user": func(ctx context.Context) string {
+				user, _ := ctx.Value(benchKey{}).(string)
+				return user
+			},
+		},
+	})
+	register(metric_info.MetricInfo{Type: metric_info.Counter, Name: "context_total", Help: "context labels", Labels: []string{"method", "tenant"}})
+	register(metric_info.MetricInfo{Type: metric_info.Gauge, Name: "ttl", Help: "ttl", Labels: []string{"method"}, TTL: time.Hour})
+
+	ctx := context.Background()
+	handlerCtx := context.WithValue(ctx, benchKey{}, "alice")
+	labelCtx := metric_info.ContextWithLabels(ctx, map[string]string{"tenant": "t1"})
+	method := map[string]string{"method": "GET"}
+
+	cases := []struct {
+		name   string
+		ctx    context.Context
+		metric metric_info.MetricName
+		labels map[string]string
+		add    bool
+	}{
+		{"CacheHit", ctx, "counter_total", map[string]string{"method": "GET", "status": "200"}, false},
+		{"Histogram", ctx, "histogram", method, false},
+		{"Add", ctx, "counter_total", map[string]string{"method": "GET", "status": "200"}, true},
+		{"LabelHandler", handlerCtx, "handler_total", method, false},
+		{"ContextLabels", labelCtx, "context_total", method, false},
+		{"TTL", ctx, "ttl", method, false},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				report := pm.Report
+				if c.add {
+					report = pm.Add
+				}
+				if err := report(c.ctx, c.metric, c.labels, 1); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
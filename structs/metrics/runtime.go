@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+	"github.com/everfir/metrics-go/structs/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// registerRuntimeCollectors 根据配置注册 Go 运行时、进程以及构建信息相关的标准指标
+func (pm *PrometheusMetrics) registerRuntimeCollectors(cfg *config.MetricsConfig) {
+	if cfg.GoCollector {
+		var rules []collectors.GoRuntimeMetricsRule
+		for _, pattern := range cfg.GoRuntimeMetrics {
+			matcher, err := regexp.Compile(pattern)
+			if err != nil {
+				logger.Warn(context.TODO(), "invalid go runtime metrics rule",
+					field.String("rule", pattern),
+					field.String("err", err.Error()),
+				)
+				continue
+			}
+			rules = append(rules, collectors.GoRuntimeMetricsRule{Matcher: matcher})
+		}
+		if len(rules) > 0 {
+			pm.registry.MustRegister(collectors.NewGoCollector(collectors.WithGoCollectorRuntimeMetrics(rules...)))
+		} else {
+			pm.registry.MustRegister(collectors.NewGoCollector())
+		}
+	}
+
+	if cfg.ProcessCollector {
+		pm.registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+
+	if cfg.BuildInfoCollector {
+		pm.registry.MustRegister(collectors.NewBuildInfoCollector())
+		pm.registry.MustRegister(newBuildInfoGauge(cfg))
+	}
+}
+
+// newBuildInfoGauge 创建值恒为 1 的 build_info 指标，版本信息优先使用通过 ldflags 注入的值
+func newBuildInfoGauge(cfg *config.MetricsConfig) prometheus.Gauge {
+	version, commit, path := cfg.Version, cfg.Commit, os.Args[0]
+	if info, ok := debug.ReadBuildInfo(); ok {
+		path = info.Path
+		if version == "" {
+			version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && commit == "" {
+				commit = setting.Value
+			}
+		}
+	}
+
+	labels := prometheus.Labels{}
+	for k, v := range cfg.ConstLabels {
+		labels[k] = v
+	}
+	labels["version"] = version
+	labels["commit"] = commit
+	labels["go_version"] = runtime.Version()
+	labels["path"] = path
+
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   cfg.Namespace,
+		Subsystem:   cfg.Subsystem,
+		Name:        "build_info",
+		Help:        "构建信息，值恒为 1",
+		ConstLabels: labels,
+	})
+	gauge.Set(1)
+	return gauge
+}
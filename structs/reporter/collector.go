@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/everfir/metrics-go/structs/config"
 	"github.com/everfir/metrics-go/structs/metric_info"
 	"github.com/everfir/metrics-go/structs/metrics"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -15,13 +16,19 @@ type CollectorReporter struct {
 	server  *http.Server
 }
 
+// NewCollectorReporter 创建一个在 port 端口暴露 /metrics 的 CollectorReporter，其余配置使用默认值
 func NewCollectorReporter(namespace, subsystem string, port int) *CollectorReporter {
-	m := metrics.New(namespace, subsystem)
+	return NewCollectorReporterWithConfig(&config.MetricsConfig{Namespace: namespace, Subsystem: subsystem, Port: port})
+}
+
+// NewCollectorReporterWithConfig 根据配置创建一个 CollectorReporter
+func NewCollectorReporterWithConfig(cfg *config.MetricsConfig) *CollectorReporter {
+	m := metrics.NewWithConfig(cfg)
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.HandlerFor(m.GetRegistry(), promhttp.HandlerOpts{}))
 
 	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
 		Handler: mux,
 	}
 
@@ -37,14 +44,35 @@ func NewCollectorReporter(namespace, subsystem string, port int) *CollectorRepor
 	}
 }
 
-func (c *CollectorReporter) Register(info metric_info.MetricInfo) {
-	c.metrics.Register(info)
+func (c *CollectorReporter) Register(info metric_info.MetricInfo) error {
+	return c.metrics.Register(info)
+}
+
+func (c *CollectorReporter) Unregister(name metric_info.MetricName) bool {
+	return c.metrics.Unregister(name)
+}
+
+func (c *CollectorReporter) Info(name metric_info.MetricName) (metric_info.MetricInfo, bool) {
+	return c.metrics.Info(name)
+}
+
+func (c *CollectorReporter) Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) error {
+	return c.metrics.Report(ctx, name, labels, value)
+}
+
+func (c *CollectorReporter) Add(ctx context.Context, name metric_info.MetricName, labels map[string]string, delta float64) error {
+	return c.metrics.Add(ctx, name, labels, delta)
+}
+
+func (c *CollectorReporter) DeleteLabelValues(name metric_info.MetricName, values ...string) (bool, error) {
+	return c.metrics.DeleteLabelValues(name, values...)
 }
 
-func (c *CollectorReporter) Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) {
-	c.metrics.Report(ctx, name, labels, value)
+func (c *CollectorReporter) DeletePartialMatch(name metric_info.MetricName, labels map[string]string) (int, error) {
+	return c.metrics.DeletePartialMatch(name, labels)
 }
 
 func (c *CollectorReporter) Close(ctx context.Context) error {
+	c.metrics.Close()
 	return c.server.Shutdown(ctx)
 }
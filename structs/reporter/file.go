@@ -0,0 +1,274 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+	"github.com/everfir/metrics-go/structs/config"
+	"github.com/everfir/metrics-go/structs/metric_info"
+	"github.com/everfir/metrics-go/structs/metrics"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// FileReporter 定期（以及在 Close 时）将指标写入文件或标准输出，适用于 CI 与离线批处理任务
+type FileReporter struct {
+	metrics *metrics.PrometheusMetrics
+	path    string
+	format  config.FileFormat
+	ticker  *time.Ticker
+	done    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+
+	closeOnce sync.Once
+}
+
+// NewFileReporter 创建一个 FileReporter，path 为空或为 "-" 时写入标准输出；interval <= 0 时仅在 Close 时写入
+func NewFileReporter(namespace, subsystem, path string, format config.FileFormat, interval time.Duration) *FileReporter {
+	return NewFileReporterWithConfig(&config.MetricsConfig{
+		Namespace:     namespace,
+		Subsystem:     subsystem,
+		FilePath:      path,
+		FileFormat:    format,
+		FlushInterval: interval,
+	})
+}
+
+// NewFileReporterWithConfig 根据配置创建一个 FileReporter，FilePath 为空或为 "-" 时写入标准输出；FlushInterval <= 0 时仅在 Close 时写入
+func NewFileReporterWithConfig(cfg *config.MetricsConfig) *FileReporter {
+	reporter := &FileReporter{
+		metrics: metrics.NewWithConfig(cfg),
+		path:    cfg.FilePath,
+		format:  cfg.FileFormat,
+		done:    make(chan struct{}),
+	}
+
+	if cfg.FlushInterval > 0 {
+		reporter.ticker = time.NewTicker(cfg.FlushInterval)
+		reporter.wg.Add(1)
+		go reporter.startWriting()
+	}
+
+	return reporter
+}
+
+func (f *FileReporter) startWriting() {
+	defer f.wg.Done()
+	for {
+		select {
+		case <-f.ticker.C:
+			if err := f.Flush(); err != nil {
+				logger.Warn(context.TODO(), "Could not write metrics file", field.String("err", err.Error()))
+			}
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// Flush 立即将当前指标写出
+func (f *FileReporter) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	mfs, err := f.metrics.GetRegistry().Gather()
+	if err != nil {
+		return fmt.Errorf("gather metrics: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := encodeMetricFamilies(&buf, mfs, f.format); err != nil {
+		return err
+	}
+
+	if f.path == "" || f.path == "-" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return writeFileAtomic(f.path, buf.Bytes())
+}
+
+func (f *FileReporter) Register(info metric_info.MetricInfo) error {
+	return f.metrics.Register(info)
+}
+
+func (f *FileReporter) Unregister(name metric_info.MetricName) bool {
+	return f.metrics.Unregister(name)
+}
+
+func (f *FileReporter) Info(name metric_info.MetricName) (metric_info.MetricInfo, bool) {
+	return f.metrics.Info(name)
+}
+
+func (f *FileReporter) Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) error {
+	return f.metrics.Report(ctx, name, labels, value)
+}
+
+func (f *FileReporter) Add(ctx context.Context, name metric_info.MetricName, labels map[string]string, delta float64) error {
+	return f.metrics.Add(ctx, name, labels, delta)
+}
+
+func (f *FileReporter) DeleteLabelValues(name metric_info.MetricName, values ...string) (bool, error) {
+	return f.metrics.DeleteLabelValues(name, values...)
+}
+
+func (f *FileReporter) DeletePartialMatch(name metric_info.MetricName, labels map[string]string) (int, error) {
+	return f.metrics.DeletePartialMatch(name, labels)
+}
+
+// Close 停止定期写出并写出最后一次，可以重复调用
+func (f *FileReporter) Close(ctx context.Context) error {
+	f.closeOnce.Do(func() {
+		f.metrics.Close()
+		if f.ticker != nil {
+			f.ticker.Stop()
+			close(f.done)
+			f.wg.Wait()
+		}
+	})
+	return f.Flush()
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再重命名，保证 node_exporter textfile collector 不会读到半截文件
+func writeFileAtomic(path string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func encodeMetricFamilies(w io.Writer, mfs []*dto.MetricFamily, format config.FileFormat) error {
+	switch format {
+	case config.FormatText, config.FormatOpenMetrics:
+		t := expfmt.TypeTextPlain
+		if format == config.FormatOpenMetrics {
+			t = expfmt.TypeOpenMetrics
+		}
+		enc := expfmt.NewEncoder(w, expfmt.NewFormat(t))
+		for _, mf := range mfs {
+			if err := enc.Encode(mf); err != nil {
+				return fmt.Errorf("encode metric family %s: %w", mf.GetName(), err)
+			}
+		}
+		if closer, ok := enc.(expfmt.Closer); ok {
+			return closer.Close()
+		}
+		return nil
+	case config.FormatJSONLines:
+		enc := json.NewEncoder(w)
+		now := time.Now().UnixMilli()
+		for _, mf := range mfs {
+			for _, m := range mf.GetMetric() {
+				if err := enc.Encode(newJSONSample(mf, m, now)); err != nil {
+					return fmt.Errorf("encode metric family %s: %w", mf.GetName(), err)
+				}
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid file format: %v", format)
+	}
+}
+
+// jsonSample 是 JSON Lines 格式中的一行，对应一条时间序列
+type jsonSample struct {
+	Name        string               `json:"name"`
+	Type        string               `json:"type"`
+	Help        string               `json:"help,omitempty"`
+	Labels      map[string]string    `json:"labels,omitempty"`
+	Value       *jsonFloat           `json:"value,omitempty"`
+	Count       *uint64              `json:"count,omitempty"`
+	Sum         *jsonFloat           `json:"sum,omitempty"`
+	Buckets     map[string]uint64    `json:"buckets,omitempty"`
+	Quantiles   map[string]jsonFloat `json:"quantiles,omitempty"`
+	TimestampMs int64                `json:"timestamp_ms"`
+}
+
+// jsonFloat 将 NaN 与 Inf 编码为字符串，避免 encoding/json 报错
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return json.Marshal(strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return json.Marshal(v)
+}
+
+func newJSONSample(mf *dto.MetricFamily, m *dto.Metric, now int64) jsonSample {
+	s := jsonSample{
+		Name:        mf.GetName(),
+		Type:        strings.ToLower(mf.GetType().String()),
+		Help:        mf.GetHelp(),
+		TimestampMs: now,
+	}
+	if m.TimestampMs != nil {
+		s.TimestampMs = m.GetTimestampMs()
+	}
+	if len(m.GetLabel()) > 0 {
+		s.Labels = make(map[string]string, len(m.GetLabel()))
+		for _, lp := range m.GetLabel() {
+			s.Labels[lp.GetName()] = lp.GetValue()
+		}
+	}
+
+	value := func(v float64) *jsonFloat {
+		f := jsonFloat(v)
+		return &f
+	}
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		s.Value = value(m.GetCounter().GetValue())
+	case dto.MetricType_GAUGE:
+		s.Value = value(m.GetGauge().GetValue())
+	case dto.MetricType_UNTYPED:
+		s.Value = value(m.GetUntyped().GetValue())
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		h := m.GetHistogram()
+		count := h.GetSampleCount()
+		s.Count, s.Sum = &count, value(h.GetSampleSum())
+		s.Buckets = make(map[string]uint64, len(h.GetBucket()))
+		for _, b := range h.GetBucket() {
+			s.Buckets[strconv.FormatFloat(b.GetUpperBound(), 'g', -1, 64)] = b.GetCumulativeCount()
+		}
+	case dto.MetricType_SUMMARY:
+		sm := m.GetSummary()
+		count := sm.GetSampleCount()
+		s.Count, s.Sum = &count, value(sm.GetSampleSum())
+		s.Quantiles = make(map[string]jsonFloat, len(sm.GetQuantile()))
+		for _, q := range sm.GetQuantile() {
+			s.Quantiles[strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64)] = jsonFloat(q.GetValue())
+		}
+	}
+	return s
+}
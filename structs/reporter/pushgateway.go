@@ -2,10 +2,12 @@ package reporter
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/everfir/logger-go"
 	"github.com/everfir/logger-go/structs/field"
+	"github.com/everfir/metrics-go/structs/config"
 	"github.com/everfir/metrics-go/structs/metric_info"
 	"github.com/everfir/metrics-go/structs/metrics"
 	"github.com/prometheus/client_golang/prometheus/push"
@@ -17,42 +19,101 @@ type PushgatewayReporter struct {
 	pushAddr  string
 	jobName   string
 	pushTimer *time.Ticker
+	done      chan struct{}
+	wg        sync.WaitGroup
+
+	closeOnce sync.Once
+	closeErr  error
 }
 
+// NewPushgatewayReporter 创建一个定期推送到 Pushgateway 的 PushgatewayReporter，其余配置使用默认值
 func NewPushgatewayReporter(namespace, subsystem, pushAddr, jobName string, pushInterval time.Duration) *PushgatewayReporter {
-	m := metrics.New(namespace, subsystem)
-	pusher := push.New(pushAddr, jobName).Gatherer(m.GetRegistry())
+	return NewPushgatewayReporterWithConfig(&config.MetricsConfig{
+		Namespace:    namespace,
+		Subsystem:    subsystem,
+		PushAddr:     pushAddr,
+		JobName:      jobName,
+		PushInterval: pushInterval,
+	})
+}
+
+// NewPushgatewayReporterWithConfig 根据配置创建一个 PushgatewayReporter
+func NewPushgatewayReporterWithConfig(cfg *config.MetricsConfig) *PushgatewayReporter {
+	// 常量标签只作为 grouping key，由 Pushgateway 附加到推送的所有指标上，同时避免不同实例的推送相互覆盖；
+	// 指标上已经带有同名标签时 Pushgateway 会拒绝推送，因此不再作为指标的常量标签
+	metricsCfg := *cfg
+	metricsCfg.ConstLabels = nil
+	m := metrics.NewWithConfig(&metricsCfg)
+	pusher := push.New(cfg.PushAddr, cfg.JobName).Gatherer(m.GetRegistry())
+	for k, v := range cfg.ConstLabels {
+		pusher = pusher.Grouping(k, v)
+	}
 
 	reporter := &PushgatewayReporter{
 		metrics:   m,
 		pusher:    pusher,
-		pushAddr:  pushAddr,
-		jobName:   jobName,
-		pushTimer: time.NewTicker(pushInterval),
+		pushAddr:  cfg.PushAddr,
+		jobName:   cfg.JobName,
+		pushTimer: time.NewTicker(cfg.PushInterval),
+		done:      make(chan struct{}),
 	}
 
+	reporter.wg.Add(1)
 	go reporter.startPushing()
 
 	return reporter
 }
 
 func (p *PushgatewayReporter) startPushing() {
-	for range p.pushTimer.C {
-		if err := p.pusher.Push(); err != nil {
-			logger.Warn(context.TODO(), "Could not push to Pushgateway", field.String("err", err.Error()))
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.pushTimer.C:
+			if err := p.pusher.Push(); err != nil {
+				logger.Warn(context.TODO(), "Could not push to Pushgateway", field.String("err", err.Error()))
+			}
+		case <-p.done:
+			return
 		}
 	}
 }
 
-func (p *PushgatewayReporter) Register(info metric_info.MetricInfo) {
-	p.metrics.Register(info)
+func (p *PushgatewayReporter) Register(info metric_info.MetricInfo) error {
+	return p.metrics.Register(info)
+}
+
+func (p *PushgatewayReporter) Unregister(name metric_info.MetricName) bool {
+	return p.metrics.Unregister(name)
+}
+
+func (p *PushgatewayReporter) Info(name metric_info.MetricName) (metric_info.MetricInfo, bool) {
+	return p.metrics.Info(name)
+}
+
+func (p *PushgatewayReporter) Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) error {
+	return p.metrics.Report(ctx, name, labels, value)
+}
+
+func (p *PushgatewayReporter) Add(ctx context.Context, name metric_info.MetricName, labels map[string]string, delta float64) error {
+	return p.metrics.Add(ctx, name, labels, delta)
+}
+
+func (p *PushgatewayReporter) DeleteLabelValues(name metric_info.MetricName, values ...string) (bool, error) {
+	return p.metrics.DeleteLabelValues(name, values...)
 }
 
-func (p *PushgatewayReporter) Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) {
-	p.metrics.Report(ctx, name, labels, value)
+func (p *PushgatewayReporter) DeletePartialMatch(name metric_info.MetricName, labels map[string]string) (int, error) {
+	return p.metrics.DeletePartialMatch(name, labels)
 }
 
+// Close 停止定期推送，等待异步队列处理完毕后再推送一次，避免丢失最后一个周期内的数据
 func (p *PushgatewayReporter) Close(ctx context.Context) error {
-	p.pushTimer.Stop()
-	return nil
+	p.closeOnce.Do(func() {
+		p.pushTimer.Stop()
+		close(p.done)
+		p.wg.Wait()
+		p.metrics.Close()
+		p.closeErr = p.pusher.PushContext(ctx)
+	})
+	return p.closeErr
 }
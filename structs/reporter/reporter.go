@@ -8,7 +8,12 @@ import (
 
 // MetricsReporter 定义了指标上报的接口
 type MetricsReporter interface {
-	Register(info metric_info.MetricInfo)
-	Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64)
+	Register(info metric_info.MetricInfo) error
+	Unregister(name metric_info.MetricName) bool
+	Info(name metric_info.MetricName) (metric_info.MetricInfo, bool)
+	Report(ctx context.Context, name metric_info.MetricName, labels map[string]string, value float64) error
+	Add(ctx context.Context, name metric_info.MetricName, labels map[string]string, delta float64) error
+	DeleteLabelValues(name metric_info.MetricName, values ...string) (bool, error)
+	DeletePartialMatch(name metric_info.MetricName, labels map[string]string) (int, error)
 	Close(ctx context.Context) error
 }
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/everfir/metrics-go/structs/metric_info"
+)
+
+// 结果标签的取值
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// DefaultResultLabel 是 Timer.StopWithError 默认使用的结果标签名
+const DefaultResultLabel = "result"
+
+// Timer 用于测量一段代码的耗时，并按照指标声明的 Unit 上报
+type Timer struct {
+	ctx         context.Context
+	name        metric_info.MetricName
+	labels      map[string]string
+	start       time.Time
+	resultLabel string
+}
+
+// TimerOption 定义了 Timer 的可选配置
+type TimerOption func(*Timer)
+
+// WithResultLabel 设置 StopWithError 记录成功或失败时使用的标签名
+func WithResultLabel(label string) TimerOption {
+	return func(t *Timer) {
+		t.resultLabel = label
+	}
+}
+
+// StartTimer 开始计时，通常配合 defer 使用：
+//
+//	defer metrics.StartTimer(ctx, name, labels).Stop()
+func StartTimer(ctx context.Context, name metric_info.MetricName, labels map[string]string, opts ...TimerOption) *Timer {
+	t := &Timer{
+		ctx:         ctx,
+		name:        name,
+		labels:      labels,
+		start:       time.Now(),
+		resultLabel: DefaultResultLabel,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Stop 结束计时并上报耗时
+func (t *Timer) Stop() time.Duration {
+	d := time.Since(t.start)
+	ObserveDuration(t.ctx, t.name, t.labels, d)
+	return d
+}
+
+// StopWithError 结束计时并上报耗时，同时根据 err 将结果标签设置为 success 或 failure：
+//
+//	t := metrics.StartTimer(ctx, name, labels)
+//	defer func() { t.StopWithError(err) }()
+func (t *Timer) StopWithError(err error) time.Duration {
+	d := time.Since(t.start)
+	labels := make(map[string]string, len(t.labels)+1)
+	for k, v := range t.labels {
+		labels[k] = v
+	}
+	labels[t.resultLabel] = ResultSuccess
+	if err != nil {
+		labels[t.resultLabel] = ResultFailure
+	}
+	ObserveDuration(t.ctx, t.name, labels, d)
+	return d
+}
+
+// ObserveDuration 按照指标声明的 Unit 上报时长，未声明时间单位时按秒上报
+func ObserveDuration(ctx context.Context, name metric_info.MetricName, labels map[string]string, d time.Duration) error {
+	if r == nil {
+		panic("metrics not initialized, call Init() first")
+	}
+	info, _ := r.Info(name)
+	return Report(ctx, name, labels, info.DurationValue(d))
+}
+
+// ObserveSince 上报从 start 到现在的时长
+func ObserveSince(ctx context.Context, name metric_info.MetricName, labels map[string]string, start time.Time) error {
+	return ObserveDuration(ctx, name, labels, time.Since(start))
+}